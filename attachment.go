@@ -0,0 +1,40 @@
+package pbin
+
+import (
+	"encoding/base64"
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// makeDataURI encodes an attachment the way the privatebin web ui expects it
+func makeDataURI(name string, b []byte) string {
+	mimeType := mime.TypeByExtension(filepath.Ext(name))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(b)
+	}
+	mimeType = strings.TrimSpace(strings.Split(mimeType, ";")[0])
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(b)
+}
+
+func parseDataURI(uri string) ([]byte, error) {
+	if !strings.HasPrefix(uri, "data:") {
+		return nil, errors.New("attachment is not a data uri")
+	}
+	i := strings.Index(uri, ",")
+	if i < 0 {
+		return nil, errors.New("attachment is not a data uri")
+	}
+	header, data := uri[len("data:"):i], uri[i+1:]
+	if strings.HasSuffix(header, ";base64") {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	d, err := url.PathUnescape(data)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(d), nil
+}
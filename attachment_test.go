@@ -0,0 +1,56 @@
+package pbin
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDataURI(t *testing.T) {
+	b := []byte{0x89, 'P', 'N', 'G', 0, 1, 2}
+	uri := makeDataURI("dir/pic.png", b)
+	if !strings.HasPrefix(uri, "data:image/png;base64,") {
+		t.Errorf("uri %s", uri)
+	}
+	got, err := parseDataURI(uri)
+	if err != nil || !bytes.Equal(got, b) {
+		t.Errorf("got %v, %v", got, err)
+	}
+	// the web ui also takes percent encoded data
+	if got, err = parseDataURI("data:text/plain,a%20b"); err != nil || string(got) != "a b" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	for _, uri := range []string{"https://example.org/", "data:text/plain;base64", "data:;base64,!!"} {
+		if _, err = parseDataURI(uri); err == nil {
+			t.Errorf("%s: no error", uri)
+		}
+	}
+}
+
+func TestAttachment(t *testing.T) {
+	pings := int32(0)
+	c := copiesClient(t, &pings)
+	plain, files := newTestHost(t), newTestHost(t)
+	addTestHost(t, c, plain, false)
+	u, _ := parseHostURL(files.URL + "/")
+	c.hosts.addHost(&host{api: u, expiry: []Expiry{Week}, features: []Feature{UploadFile}})
+	p, _ := c.CraftPaste([]byte("see the file"))
+	p.SetAttachment("/tmp/report.txt", []byte("attached"))
+	r, err := p.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only the host that takes files is picked
+	if r.Host != files.URL+"/" || plain.pastes(t) != 0 {
+		t.Fatalf("sent to %s", r.Host)
+	}
+	pu, _ := r.PasteURL()
+	got, err := c.OpenPaste(pu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, b := got.Attachment()
+	if name != "report.txt" || string(b) != "attached" || string(got.Text()) != "see the file" {
+		t.Errorf("attachment %q %q, text %q", name, b, got.Text())
+	}
+}
@@ -0,0 +1,138 @@
+package pbin
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+)
+
+type (
+	// ccm is aes-ccm the way sjcl does it: the iv is cut down to leave room
+	// for the message length, so the nonce size depends on the message
+	ccm struct {
+		b       cipher.Block
+		tagSize int // bytes
+		ivSize  int // bytes
+	}
+)
+
+func newCCM(b cipher.Block, tagSize int, ivSize int) (cipher.AEAD, error) {
+	if b.BlockSize() != 16 {
+		return nil, errors.New("ccm: needs a 128 bit block cipher")
+	}
+	if tagSize < 4 || tagSize > 16 || tagSize%2 != 0 {
+		return nil, errors.New("ccm: invalid tag size")
+	}
+	if ivSize < 7 {
+		return nil, errors.New("ccm: iv must be at least 7 bytes")
+	}
+	return &ccm{b: b, tagSize: tagSize, ivSize: ivSize}, nil
+}
+
+func (c *ccm) NonceSize() int {
+	return c.ivSize
+}
+
+func (c *ccm) Overhead() int {
+	return c.tagSize
+}
+
+// lengthSize is the byte count of the message length field, L in the ccm spec
+func (c *ccm) lengthSize(n int) int {
+	l := 2
+	for l < 4 && n>>(8*uint(l)) != 0 {
+		l++
+	}
+	if l < 15-c.ivSize {
+		l = 15 - c.ivSize
+	}
+	return l
+}
+
+func (c *ccm) Seal(dst []byte, iv []byte, plaintext []byte, adata []byte) []byte {
+	l := c.lengthSize(len(plaintext))
+	nonce := iv[:15-l]
+	tag := c.mac(nonce, l, plaintext, adata)
+	ct := c.ctr(nonce, l, tag, plaintext)
+	return append(append(dst, ct...), tag...)
+}
+
+func (c *ccm) Open(dst []byte, iv []byte, ciphertext []byte, adata []byte) ([]byte, error) {
+	if len(ciphertext) < c.tagSize {
+		return nil, errors.New("ccm: message authentication failed")
+	}
+	n := len(ciphertext) - c.tagSize
+	l := c.lengthSize(n)
+	nonce := iv[:15-l]
+	tag := append([]byte{}, ciphertext[n:]...)
+	plaintext := c.ctr(nonce, l, tag, ciphertext[:n])
+	if subtle.ConstantTimeCompare(tag, c.mac(nonce, l, plaintext, adata)) != 1 {
+		return nil, errors.New("ccm: message authentication failed")
+	}
+	return append(dst, plaintext...), nil
+}
+
+// mac is the cbc-mac over the flags, nonce, length, adata and plaintext
+func (c *ccm) mac(nonce []byte, l int, plaintext []byte, adata []byte) []byte {
+	x := make([]byte, 16)
+	x[0] = byte((c.tagSize-2)<<2 | (l - 1))
+	if len(adata) > 0 {
+		x[0] |= 1 << 6
+	}
+	copy(x[1:], nonce)
+	for i := 0; i < l; i++ {
+		x[15-i] = byte(len(plaintext) >> (8 * uint(i)))
+	}
+	c.b.Encrypt(x, x)
+	blocks := []byte{}
+	if len(adata) > 0 {
+		if len(adata) <= 0xfeff {
+			blocks = append(blocks, byte(len(adata)>>8), byte(len(adata)))
+		} else {
+			blocks = append(blocks, 0xff, 0xfe, byte(len(adata)>>24), byte(len(adata)>>16), byte(len(adata)>>8), byte(len(adata)))
+		}
+		blocks = append(blocks, adata...)
+		blocks = append(blocks, make([]byte, (16-len(blocks)%16)%16)...)
+	}
+	blocks = append(blocks, plaintext...)
+	blocks = append(blocks, make([]byte, (16-len(blocks)%16)%16)...)
+	for i := 0; i < len(blocks); i += 16 {
+		xorBytes(x, x, blocks[i:i+16])
+		c.b.Encrypt(x, x)
+	}
+	return x[:c.tagSize]
+}
+
+// ctr encrypts tag in place with counter 0 and returns data xored with counters 1 and up
+func (c *ccm) ctr(nonce []byte, l int, tag []byte, data []byte) []byte {
+	ctr := make([]byte, 16)
+	ctr[0] = byte(l - 1)
+	copy(ctr[1:], nonce)
+	s := make([]byte, 16)
+	c.b.Encrypt(s, ctr)
+	xorBytes(tag, tag, s)
+	out := make([]byte, len(data))
+	for i := 0; i < len(data); i += 16 {
+		for j := 15; j > 15-l; j-- {
+			ctr[j]++
+			if ctr[j] != 0 {
+				break
+			}
+		}
+		c.b.Encrypt(s, ctr)
+		xorBytes(out[i:], data[i:], s)
+	}
+	return out
+}
+
+// xorBytes sets dst to a xor b up to the shorter of the two and returns the count
+func xorBytes(dst []byte, a []byte, b []byte) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		dst[i] = a[i] ^ b[i]
+	}
+	return n
+}
@@ -0,0 +1,65 @@
+package pbin
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+)
+
+// the examples of nist sp 800-38c appendix c
+func TestCCMNISTVectors(t *testing.T) {
+	tests := []struct {
+		nonce, adata, plaintext, ciphertext string
+		tagSize                             int
+	}{
+		{
+			nonce:      "10111213141516",
+			adata:      "0001020304050607",
+			plaintext:  "20212223",
+			ciphertext: "7162015b4dac255d",
+			tagSize:    4,
+		},
+		{
+			nonce:      "1011121314151617",
+			adata:      "000102030405060708090a0b0c0d0e0f",
+			plaintext:  "202122232425262728292a2b2c2d2e2f",
+			ciphertext: "d2a1f0e051ea5f62081a7792073d593d1fc64fbfaccd",
+			tagSize:    6,
+		},
+		{
+			nonce:      "101112131415161718191a1b",
+			adata:      "000102030405060708090a0b0c0d0e0f10111213",
+			plaintext:  "202122232425262728292a2b2c2d2e2f3031323334353637",
+			ciphertext: "e3b201a9f5b71a7a9b1ceaeccd97e70b6176aad9a4428aa5484392fbc1b09951",
+			tagSize:    8,
+		},
+	}
+	key, _ := hex.DecodeString("404142434445464748494a4b4c4d4e4f")
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tt := range tests {
+		nonce, _ := hex.DecodeString(tt.nonce)
+		adata, _ := hex.DecodeString(tt.adata)
+		plaintext, _ := hex.DecodeString(tt.plaintext)
+		want, _ := hex.DecodeString(tt.ciphertext)
+		aead, err := newCCM(b, tt.tagSize, len(nonce))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := aead.Seal(nil, nonce, plaintext, adata)
+		if !bytes.Equal(got, want) {
+			t.Errorf("example %d: seal got %x, want %x", i+1, got, want)
+		}
+		opened, err := aead.Open(nil, nonce, want, adata)
+		if err != nil || !bytes.Equal(opened, plaintext) {
+			t.Errorf("example %d: open got %x, %v", i+1, opened, err)
+		}
+		want[0] ^= 1
+		if _, err = aead.Open(nil, nonce, want, adata); err == nil {
+			t.Errorf("example %d: a changed ciphertext was opened", i+1)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package pbin
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	maxKDFIterations int = 10000000 // keeps a hostile paste from pinning the cpu
+	// MaxInflatedSize caps what a paste may inflate to when it is opened, five times
+	// the 10 MB privatebin takes by default, so a hostile host can not send a deflate bomb
+	MaxInflatedSize int64 = 50 * 1000 * 1000
+)
+
+var (
+	ErrUnsupportedCipher = errors.New("unsupported cipher spec")
+)
+
+type (
+	// CipherSpec is the first element of a v2 adata:
+	// [iv, salt, iterations, key size, tag size, algorithm, mode, compression],
+	// v1 pastes carry the same parameters in their sjcl json
+	CipherSpec struct {
+		IV          []byte
+		Salt        []byte
+		Iterations  int
+		KeySize     int // bits
+		TagSize     int // bits
+		Algorithm   string
+		Mode        string
+		Compression string
+	}
+)
+
+// parseCipherSpec reads and validates the spec of a paste or comment
+func parseCipherSpec(v interface{}) (*CipherSpec, error) {
+	spec, ok := v.([]interface{})
+	if !ok || len(spec) != 8 {
+		return nil, ErrInvalidPaste
+	}
+	cs := &CipherSpec{}
+	strs := []*string{nil, nil, nil, nil, nil, &cs.Algorithm, &cs.Mode, &cs.Compression}
+	ints := []*int{nil, nil, &cs.Iterations, &cs.KeySize, &cs.TagSize, nil, nil, nil}
+	for i, e := range spec {
+		switch {
+		case i < 2:
+			{
+				s, ok := e.(string)
+				if !ok {
+					return nil, ErrInvalidPaste
+				}
+				b, err := decodeBase64(s)
+				if err != nil {
+					return nil, ErrInvalidPaste
+				}
+				if i == 0 {
+					cs.IV = b
+				} else {
+					cs.Salt = b
+				}
+			}
+		case ints[i] != nil:
+			{
+				n, ok := e.(float64)
+				if !ok || n != float64(int(n)) {
+					return nil, ErrInvalidPaste
+				}
+				*ints[i] = int(n)
+			}
+		case strs[i] != nil:
+			{
+				s, ok := e.(string)
+				if !ok {
+					return nil, ErrInvalidPaste
+				}
+				*strs[i] = s
+			}
+		}
+	}
+	return cs, cs.validate()
+}
+
+func (cs *CipherSpec) validate() error {
+	switch {
+	case cs.Algorithm != "aes":
+		{
+			return fmt.Errorf("%w: algorithm %q", ErrUnsupportedCipher, cs.Algorithm)
+		}
+	// ccm is the sjcl default, zerobin pastes use it
+	case cs.Mode != "gcm" && cs.Mode != "ccm":
+		{
+			return fmt.Errorf("%w: mode %q", ErrUnsupportedCipher, cs.Mode)
+		}
+	case cs.KeySize != 128 && cs.KeySize != 192 && cs.KeySize != 256:
+		{
+			return fmt.Errorf("%w: key size %d", ErrUnsupportedCipher, cs.KeySize)
+		}
+	case cs.Mode == "gcm" && (cs.TagSize < 96 || cs.TagSize > 128 || cs.TagSize%8 != 0):
+		{
+			return fmt.Errorf("%w: tag size %d", ErrUnsupportedCipher, cs.TagSize)
+		}
+	case cs.Mode == "ccm" && (cs.TagSize < 32 || cs.TagSize > 128 || cs.TagSize%16 != 0):
+		{
+			return fmt.Errorf("%w: tag size %d", ErrUnsupportedCipher, cs.TagSize)
+		}
+	// go has no gcm with both a custom nonce and a custom tag size
+	case cs.Mode == "gcm" && cs.TagSize != 128 && len(cs.IV) != NonceSize:
+		{
+			return fmt.Errorf("%w: tag size %d with a %d byte iv", ErrUnsupportedCipher, cs.TagSize, len(cs.IV))
+		}
+	case cs.Mode == "ccm" && len(cs.IV) < 7:
+		{
+			return fmt.Errorf("%w: %d byte iv", ErrUnsupportedCipher, len(cs.IV))
+		}
+	case len(cs.IV) == 0:
+		{
+			return fmt.Errorf("%w: empty iv", ErrUnsupportedCipher)
+		}
+	case cs.Iterations < 1 || cs.Iterations > maxKDFIterations:
+		{
+			return fmt.Errorf("%w: %d kdf iterations", ErrUnsupportedCipher, cs.Iterations)
+		}
+	case cs.Compression != "zlib" && cs.Compression != "none":
+		{
+			return fmt.Errorf("%w: compression %q", ErrUnsupportedCipher, cs.Compression)
+		}
+	}
+	return nil
+}
+
+func (cs *CipherSpec) key(secret []byte, pass string) []byte {
+	if pass != "" {
+		secret = append(append([]byte{}, secret...), []byte(pass)...)
+	}
+	return pbkdf2.Key(secret, cs.Salt, cs.Iterations, cs.KeySize/8, sha256.New)
+}
+
+func (cs *CipherSpec) aead(key []byte) (cipher.AEAD, error) {
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if cs.Mode == "ccm" {
+		return newCCM(c, cs.TagSize/8, len(cs.IV))
+	}
+	if cs.TagSize != 128 {
+		return cipher.NewGCMWithTagSize(c, cs.TagSize/8)
+	}
+	return cipher.NewGCMWithNonceSize(c, len(cs.IV))
+}
+
+// open decrypts ct, adatav is authenticated exactly as the server returned it
+func (cs *CipherSpec) open(secret []byte, pass string, ct []byte, adatav interface{}) ([]byte, error) {
+	aead, err := cs.aead(cs.key(secret, pass))
+	if err != nil {
+		return nil, err
+	}
+	adata, err := json.Marshal(adatav)
+	if err != nil {
+		return nil, err
+	}
+	flated, err := aead.Open(nil, cs.IV, ct, adata)
+	if err != nil {
+		return nil, decryptFailed(pass)
+	}
+	if cs.Compression == "none" {
+		return flated, nil
+	}
+	// privatebin calls it zlib but writes raw deflate
+	return inflate(flated)
+}
+
+// inflate reads raw deflate up to MaxInflatedSize
+func inflate(b []byte) ([]byte, error) {
+	fr := flate.NewReader(bytes.NewBuffer(b))
+	defer fr.Close()
+	out, err := ioutil.ReadAll(io.LimitReader(fr, MaxInflatedSize+1))
+	if err != nil {
+		return nil, ErrInvalidPaste
+	}
+	if int64(len(out)) > MaxInflatedSize {
+		return nil, fmt.Errorf("%w: inflates past %d bytes", ErrInvalidPaste, MaxInflatedSize)
+	}
+	return out, nil
+}
+
+// openJSON decrypts ct according to the spec in adata
+func openJSON(secret []byte, pass string, ct interface{}, spec interface{}, adatav interface{}) (map[string]interface{}, error) {
+	cts, ok := ct.(string)
+	if !ok {
+		return nil, ErrInvalidPaste
+	}
+	cipherJSONData, err := decodeBase64(cts)
+	if err != nil {
+		return nil, ErrInvalidPaste
+	}
+	cs, err := parseCipherSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	clearJSONData, err := cs.open(secret, pass, cipherJSONData, adatav)
+	if err != nil {
+		return nil, err
+	}
+	pd := map[string]interface{}{}
+	err = json.Unmarshal(clearJSONData, &pd)
+	if err != nil {
+		return nil, ErrInvalidPaste
+	}
+	return pd, nil
+}
+
+func decryptFailed(pass string) error {
+	if pass != "" {
+		return ErrWrongPassword
+	}
+	return ErrWrongKey
+}
+
+// decodeBase64 takes both the unpadded base64 pbin writes and the padded one of the web ui
+func decodeBase64(s string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+}
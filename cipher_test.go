@@ -0,0 +1,131 @@
+package pbin
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"testing"
+)
+
+func testSpec() *CipherSpec {
+	return &CipherSpec{
+		IV:          bytes.Repeat([]byte{1}, NonceSize),
+		Salt:        bytes.Repeat([]byte{2}, SaltSize),
+		Iterations:  1,
+		KeySize:     256,
+		TagSize:     128,
+		Algorithm:   "aes",
+		Mode:        "gcm",
+		Compression: "zlib",
+	}
+}
+
+// seal deflates and encrypts b like a host would hand it out
+func (cs *CipherSpec) seal(t *testing.T, secret []byte, b []byte) []byte {
+	buf := &bytes.Buffer{}
+	fw, _ := flate.NewWriter(buf, flate.BestCompression)
+	fw.Write(b)
+	fw.Close()
+	aead, err := cs.aead(cs.key(secret, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return aead.Seal(nil, cs.IV, buf.Bytes(), []byte(`"adata"`))
+}
+
+func TestOpenDeflateBomb(t *testing.T) {
+	cs := testSpec()
+	secret := bytes.Repeat([]byte{3}, KDFSecretSize)
+	b, err := cs.open(secret, "", cs.seal(t, secret, []byte("small")), "adata")
+	if err != nil || string(b) != "small" {
+		t.Fatalf("got %q, %v", b, err)
+	}
+	// a few dozen kilobytes that inflate past the cap
+	bomb := cs.seal(t, secret, make([]byte, MaxInflatedSize+1))
+	if len(bomb) > 1<<20 {
+		t.Fatalf("bomb of %d bytes", len(bomb))
+	}
+	_, err = cs.open(secret, "", bomb, "adata")
+	if !errors.Is(err, ErrInvalidPaste) {
+		t.Fatalf("got %v, want ErrInvalidPaste", err)
+	}
+}
+
+func TestParseCipherSpec(t *testing.T) {
+	valid := func() []interface{} {
+		return []interface{}{"AQEBAQEBAQEBAQEB", "AgICAgICAgI=", float64(100000), float64(256), float64(128), "aes", "gcm", "zlib"}
+	}
+	cs, err := parseCipherSpec(valid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cs.IV) != NonceSize || len(cs.Salt) != SaltSize || cs.Iterations != 100000 || cs.KeySize != 256 || cs.Mode != "gcm" {
+		t.Errorf("spec %+v", cs)
+	}
+	for _, tc := range []struct {
+		i    int
+		v    interface{}
+		want error
+	}{
+		{0, "not base64!", ErrInvalidPaste},
+		{0, float64(1), ErrInvalidPaste},
+		{0, "", ErrUnsupportedCipher},
+		{1, []interface{}{}, ErrInvalidPaste},
+		{2, "100000", ErrInvalidPaste},
+		{2, float64(1.5), ErrInvalidPaste},
+		{2, float64(0), ErrUnsupportedCipher},
+		{2, float64(maxKDFIterations + 1), ErrUnsupportedCipher},
+		{3, float64(512), ErrUnsupportedCipher},
+		{4, float64(64), ErrUnsupportedCipher},
+		{4, float64(100), ErrUnsupportedCipher},
+		{5, "des", ErrUnsupportedCipher},
+		{6, "cbc", ErrUnsupportedCipher},
+		{6, true, ErrInvalidPaste},
+		{7, "gzip", ErrUnsupportedCipher},
+	} {
+		spec := valid()
+		spec[tc.i] = tc.v
+		if _, err := parseCipherSpec(spec); !errors.Is(err, tc.want) {
+			t.Errorf("element %d %v: got %v, want %v", tc.i, tc.v, err, tc.want)
+		}
+	}
+	for _, spec := range []interface{}{nil, "spec", valid()[:7], append(valid(), "extra")} {
+		if _, err := parseCipherSpec(spec); !errors.Is(err, ErrInvalidPaste) {
+			t.Errorf("%v: got %v", spec, err)
+		}
+	}
+	// gcm with a short tag needs the standard nonce size
+	spec := valid()
+	spec[0], spec[4] = "AQEBAQEBAQEBAQEBAQEBAQ==", float64(96)
+	if _, err := parseCipherSpec(spec); !errors.Is(err, ErrUnsupportedCipher) {
+		t.Errorf("96 bit tag with a 16 byte iv: got %v", err)
+	}
+	// ccm is what sjcl and zerobin use, with tags in steps of 16 bits
+	spec = valid()
+	spec[4], spec[6] = float64(64), "ccm"
+	if _, err := parseCipherSpec(spec); err != nil {
+		t.Errorf("ccm: %v", err)
+	}
+}
+
+func TestOpenHonorsSpec(t *testing.T) {
+	secret := bytes.Repeat([]byte{3}, KDFSecretSize)
+	for _, mod := range []func(cs *CipherSpec){
+		func(cs *CipherSpec) { cs.KeySize = 128 },
+		func(cs *CipherSpec) { cs.TagSize = 96 },
+		func(cs *CipherSpec) { cs.Mode, cs.TagSize, cs.IV = "ccm", 64, cs.IV[:11] },
+		func(cs *CipherSpec) { cs.Iterations = 1000 },
+	} {
+		cs := testSpec()
+		mod(cs)
+		ct := cs.seal(t, secret, []byte("spec"))
+		b, err := cs.open(secret, "", ct, "adata")
+		if err != nil || string(b) != "spec" {
+			t.Errorf("%+v: got %q, %v", cs, b, err)
+		}
+		// the same bytes under the default spec do not open
+		if _, err = testSpec().open(secret, "", ct, "adata"); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("%+v opened with the default spec: %v", cs, err)
+		}
+	}
+}
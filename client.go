@@ -0,0 +1,111 @@
+package pbin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+var (
+	// DefaultClient is used by the package level functions
+	DefaultClient = NewClient(nil)
+)
+
+type (
+	// Client holds the http client, the host registry and the paste defaults,
+	// it is safe to share between goroutines once configured
+	Client struct {
+		HTTPClient  *http.Client // nil uses http.DefaultClient
+		UserAgent   string       // sent with every request when set
+		Expiry      Expiry       // for pastes without SetExpiry, Week when unset
+		MaxAttempts int          // for pastes without SetMaxAttempts, 3 when unset
+		hosts       *db
+		dial        dialFunc // host pings, set with the transport by SetSOCKS5
+	}
+	dialFunc func(ctx context.Context, network string, addr string) (net.Conn, error)
+)
+
+// NewClient starts with the hosts compiled into pbin, httpClient may be nil
+func NewClient(httpClient *http.Client) *Client {
+	return &Client{
+		HTTPClient: httpClient,
+		hosts:      processHosts(),
+	}
+}
+
+func (c *Client) dialer() dialFunc {
+	if c.dial != nil {
+		return c.dial
+	}
+	return (&net.Dialer{}).DialContext
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return hc.Do(req)
+}
+
+// CraftPaste prepares a paste sent through the client
+func (c *Client) CraftPaste(b []byte) (*Paste, error) {
+	p := &Paste{client: c}
+	p.init(b)
+	return p, nil
+}
+
+func (c *Client) GetPaste(ur *url.URL) ([]byte, error) {
+	return c.GetPasteContext(context.Background(), ur)
+}
+
+func (c *Client) GetPasteContext(ctx context.Context, ur *url.URL) ([]byte, error) {
+	p, err := c.OpenPasteContext(ctx, ur)
+	if err != nil {
+		return nil, err
+	}
+	return p.clearTextData, nil
+}
+
+func (c *Client) OpenPaste(ur *url.URL) (*Paste, error) {
+	return c.OpenPasteContext(context.Background(), ur)
+}
+
+func (c *Client) OpenPasteContext(ctx context.Context, ur *url.URL) (*Paste, error) {
+	return c.OpenPasteWithPromptContext(ctx, ur, "", nil)
+}
+
+// OpenPasteWithPromptContext is the client version of the package level function
+func (c *Client) OpenPasteWithPromptContext(ctx context.Context, ur *url.URL, pass string, prompt func() (string, error)) (*Paste, error) {
+	hostAPI, pID, key, err := splitPasteURL(ur)
+	if err != nil {
+		return nil, err
+	}
+	err = checkKey(key)
+	if err != nil {
+		return nil, err
+	}
+	m, err := c.fetchPaste(ctx, hostAPI, pID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := c.openPaste(hostAPI, key, pass, m)
+	if errors.Is(err, ErrDecrypt) && pass == "" && prompt != nil {
+		pass, err = prompt()
+		if err != nil {
+			return nil, err
+		}
+		p, err = c.openPaste(hostAPI, key, pass, m)
+	}
+	if err != nil {
+		return nil, err
+	}
+	meta, _ := m["meta"].(map[string]interface{})
+	p.readExpiry(meta)
+	return p, nil
+}
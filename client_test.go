@@ -0,0 +1,98 @@
+package pbin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// hangingHost never answers until the test is over
+func hangingHost(t *testing.T) *httptest.Server {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { close(done) })
+	return ts
+}
+
+func TestContextDeadline(t *testing.T) {
+	ts := hangingHost(t)
+	c := NewClient(nil)
+	u, _ := url.Parse(ts.URL + "/?0011223344556677#3vQB7B6MrGQZaxCuFg4oh3vQB7B6MrGQZaxCuFg4oh")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.GetPasteContext(ctx, u)
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 5*time.Second {
+		t.Fatalf("got %v after %s", err, time.Since(start))
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	p, _ := c.CraftPaste([]byte("late"))
+	p.SetHost(ts.URL + "/")
+	start = time.Now()
+	if _, err = p.SendContext(ctx); !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 5*time.Second {
+		t.Fatalf("got %v after %s", err, time.Since(start))
+	}
+}
+
+func TestContextStopsFailover(t *testing.T) {
+	pings, posts := int32(0), int32(0)
+	c := copiesClient(t, &pings)
+	for i := 0; i < 3; i++ {
+		addTestHost(t, c, failingHost(t, http.StatusBadGateway, &posts), false)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		// the first host fails, then the caller gives up
+		res, err := http.DefaultTransport.RoundTrip(r)
+		cancel()
+		return res, err
+	})}
+	p, _ := c.CraftPaste([]byte("stop"))
+	if _, err := p.SendContext(ctx); !errors.Is(err, ErrHostDown) || posts != 1 || len(p.Attempts()) != 1 {
+		t.Errorf("got %v after %d posts", err, posts)
+	}
+}
+
+func TestClientsAreSeparate(t *testing.T) {
+	pings := int32(0)
+	a, b := copiesClient(t, &pings), NewClient(nil)
+	h := newTestHost(t)
+	addTestHost(t, a, h, false)
+	if b.hosts.getHost(h.URL+"/") != nil || DefaultClient.hosts.getHost(h.URL+"/") != nil {
+		t.Fatal("a host of one client shows up in another")
+	}
+	if len(b.hosts.hosts) == 0 {
+		t.Error("a new client has no built in hosts")
+	}
+	agents := []string{}
+	a.UserAgent = "pbin-test/1"
+	a.Expiry = Day
+	a.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		agents = append(agents, r.Header.Get("User-Agent"))
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+	p, _ := a.CraftPaste([]byte("through my transport"))
+	r, err := p.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(agents) != 1 || agents[0] != "pbin-test/1" || r.Expiry != Day {
+		t.Errorf("user agents %q, expiry %s", agents, r.Expiry)
+	}
+	u, _ := r.PasteURL()
+	got, err := a.GetPaste(u)
+	if err != nil || string(got) != "through my transport" || len(agents) != 2 {
+		t.Errorf("got %q, %v after %d requests", got, err, len(agents))
+	}
+}
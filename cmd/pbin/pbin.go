@@ -2,25 +2,68 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/cbluth/pbin"
+	"github.com/cbluth/pbin/server"
+	"golang.org/x/term"
 )
 
 var (
 	getURL         *url.URL
+	bundleURLs     []*url.URL
+	bundleFile     string
+	copies         int
 	outFile        string
+	attachFile     string
+	inFile         string
+	format         string
+	hostURL        string
+	configFile     string
+	socksAddr      string
+	maxAttempts    int
+	timeout        time.Duration
 	password       string
 	replyTo        string
+	nickname       string
+	deleteToken    string
 	setExpiry	   string
 	base64Mode     bool
+	showThread     bool
+	deleteMode     bool
+	infoMode       bool
+	serveMode      bool
+	mirrorMode     bool
+	keepKey        bool
+	listenAddr     string
+	servePrefix    string
+	dataDir        string
+	phpDataDir     string
+	certFile       string
+	keyFile        string
+	ipHeader       string
+	maxSize        int64
+	maxExpiry      time.Duration
+	rateLimit      time.Duration
+	purgeEvery     time.Duration
+	shortenURL     bool
+	noPublic       bool
+	jsonMode       bool
 	burnAfterRead  bool
 	openDiscussion bool
 )
@@ -28,8 +71,109 @@ var (
 func init() {
 	panicstr := "opening a discussion and burning after reading are mutually exclusive, cant have both"
 	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "serve" {
+		serveMode = true
+		listenAddr = "127.0.0.1:8080"
+		maxSize = 10 << 20
+		rateLimit = 10 * time.Second
+		purgeEvery = 5 * time.Minute
+	}
+	if len(args) > 0 && args[0] == "mirror" {
+		mirrorMode = true
+	}
 	for i, arg := range args {
 		switch arg {
+		case "-listen", "-addr":
+			{
+				if !(len(args) > i+1) {
+					panic("missing listen arg")
+				}
+				listenAddr = args[i+1]
+			}
+		case "-prefix":
+			{
+				if !(len(args) > i+1) {
+					panic("missing prefix arg")
+				}
+				servePrefix = args[i+1]
+			}
+		case "-data", "-datadir":
+			{
+				if !(len(args) > i+1) {
+					panic("missing data dir arg")
+				}
+				dataDir = args[i+1]
+			}
+		case "-privatebin", "-phpdata":
+			{
+				if !(len(args) > i+1) {
+					panic("missing privatebin data dir arg")
+				}
+				phpDataDir = args[i+1]
+			}
+		case "-cert", "-key":
+			{
+				if !(len(args) > i+1) {
+					panic("missing " + strings.TrimPrefix(arg, "-") + " arg")
+				}
+				if arg == "-cert" {
+					certFile = args[i+1]
+				} else {
+					keyFile = args[i+1]
+				}
+			}
+		case "-maxsize":
+			{
+				if !(len(args) > i+1) {
+					panic("missing max size arg")
+				}
+				n, err := parseSize(args[i+1])
+				if err != nil {
+					panic(err)
+				}
+				maxSize = n
+			}
+		case "-maxexpiry":
+			{
+				if !(len(args) > i+1) {
+					panic("missing max expiry arg")
+				}
+				d, err := time.ParseDuration(args[i+1])
+				if err != nil {
+					ex, perr := pbin.ParseExpiry(args[i+1])
+					if perr != nil {
+						panic(err)
+					}
+					d = server.ExpiryDuration(ex.String())
+				}
+				maxExpiry = d
+			}
+		case "-ratelimit", "-purge":
+			{
+				if !(len(args) > i+1) {
+					panic("missing " + strings.TrimPrefix(arg, "-") + " arg")
+				}
+				d, err := time.ParseDuration(args[i+1])
+				if err != nil {
+					panic(err)
+				}
+				if arg == "-ratelimit" {
+					rateLimit = d
+				} else {
+					purgeEvery = d
+				}
+			}
+		case "-ipheader":
+			{
+				if !(len(args) > i+1) {
+					panic("missing ip header arg")
+				}
+				ipHeader = args[i+1]
+			}
+		case "-samekey", "-keepkey":
+			{
+				keepKey = true
+			}
 		case "-base64", "-b64":
 			{
 				base64Mode = true
@@ -54,7 +198,7 @@ func init() {
 					panic(panicstr)
 				}
 				openDiscussion = true
-				if len(args) > i+1 {
+				if len(args) > i+1 && !strings.HasPrefix(args[i+1], "-") && !strings.HasPrefix(args[i+1], "https://") && !strings.HasPrefix(args[i+1], "http://") {
 					replyTo = args[i+1]
 				} else {
 					replyTo = "parent"
@@ -71,6 +215,127 @@ func init() {
 			{
 				setExpiry = strings.ReplaceAll(arg, "-", "")
 			}
+		case "-delete", "-del", "-rm":
+			{
+				deleteMode = true
+			}
+		case "-info", "-stat":
+			{
+				infoMode = true
+			}
+		case "-host", "-server":
+			{
+				if !(len(args) > i+1) {
+					panic("missing host arg")
+				}
+				hostURL = args[i+1]
+			}
+		case "-config":
+			{
+				if !(len(args) > i+1) {
+					panic("missing config arg")
+				}
+				configFile = args[i+1]
+			}
+		case "-attempts", "-retries":
+			{
+				if !(len(args) > i+1) {
+					panic("missing attempts arg")
+				}
+				n, err := strconv.Atoi(args[i+1])
+				if err != nil {
+					panic(err)
+				}
+				maxAttempts = n
+			}
+		case "-copies", "-redundancy":
+			{
+				if !(len(args) > i+1) {
+					panic("missing copies arg")
+				}
+				n, err := strconv.Atoi(args[i+1])
+				if err != nil {
+					panic(err)
+				}
+				copies = n
+			}
+		case "-bundle":
+			{
+				if !(len(args) > i+1) {
+					panic("missing bundle file arg")
+				}
+				bundleFile = args[i+1]
+			}
+		case "-timeout":
+			{
+				if !(len(args) > i+1) {
+					panic("missing timeout arg")
+				}
+				d, err := time.ParseDuration(args[i+1])
+				if err != nil {
+					panic(err)
+				}
+				timeout = d
+			}
+		case "-socks5", "-socks", "-proxy":
+			{
+				if !(len(args) > i+1) {
+					panic("missing socks5 arg")
+				}
+				socksAddr = args[i+1]
+			}
+		case "-tor":
+			{
+				socksAddr = "127.0.0.1:9050"
+			}
+		case "-json":
+			{
+				jsonMode = true
+			}
+		case "-nopublic", "-private":
+			{
+				noPublic = true
+			}
+		case "-short", "-shorten", "-s":
+			{
+				shortenURL = true
+			}
+		case "-thread", "-t", "-showcomments":
+			{
+				showThread = true
+			}
+		case "-nick", "-nickname", "-name":
+			{
+				if !(len(args) > i+1) {
+					panic("missing nickname arg")
+				}
+				nickname = args[i+1]
+			}
+		case "-f", "-file", "-attach", "-attachment":
+			{
+				if !(len(args) > i+1) {
+					panic("missing file arg")
+				}
+				attachFile = args[i+1]
+			}
+		case "-in", "-input":
+			{
+				if !(len(args) > i+1) {
+					panic("missing input arg")
+				}
+				inFile = args[i+1]
+			}
+		case "-format", "-fmt":
+			{
+				if !(len(args) > i+1) {
+					panic("missing format arg")
+				}
+				format = args[i+1]
+			}
+		case "-plaintext", "-markdown", "-syntaxhighlighting":
+			{
+				format = strings.TrimPrefix(arg, "-")
+			}
 		case "-o", "-out", "-output":
 			{
 				if !(len(args) > i+1) {
@@ -87,14 +352,67 @@ func init() {
 				password = args[i+1]
 			}
 		}
-		if strings.HasPrefix(arg, "https://") {
+		if (strings.HasPrefix(arg, "https://") || strings.HasPrefix(arg, "http://")) && arg != hostURL {
 			err := (error)(nil)
 			getURL, err = url.Parse(arg)
 			if err != nil {
 				panic(err)
 			}
+			bundleURLs = append(bundleURLs, getURL)
 		}
 	}
+	if deleteMode {
+		// the token is wherever it is given, after the url or before it
+		rest := positional(args)
+		if len(rest) > 1 {
+			panic("usage: pbin -delete URL [TOKEN], more than one token: " + strings.Join(rest, " "))
+		}
+		if len(rest) == 1 {
+			deleteToken = rest[0]
+		}
+	}
+}
+
+// valueFlags take the next arg as their value, keep it in step with the switch in init
+var valueFlags = map[string]bool{
+	"-listen": true, "-addr": true, "-prefix": true, "-data": true, "-datadir": true,
+	"-privatebin": true, "-phpdata": true, "-cert": true, "-key": true, "-maxsize": true,
+	"-maxexpiry": true, "-ratelimit": true, "-purge": true, "-ipheader": true,
+	"-expire": true, "-x": true, "-expiry": true, "-host": true, "-server": true,
+	"-config": true, "-attempts": true, "-retries": true, "-copies": true, "-redundancy": true,
+	"-bundle": true, "-timeout": true, "-socks5": true, "-socks": true, "-proxy": true,
+	"-nick": true, "-nickname": true, "-name": true, "-f": true, "-file": true,
+	"-attach": true, "-attachment": true, "-in": true, "-input": true, "-format": true,
+	"-fmt": true, "-o": true, "-out": true, "-output": true, "-p": true, "-pass": true,
+	"-password": true,
+}
+
+// positional are the args that are no flag, flag value, url or subcommand
+func positional(args []string) []string {
+	rest := []string{}
+	for i, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "-"), strings.HasPrefix(arg, "https://"), strings.HasPrefix(arg, "http://"):
+			{
+				continue
+			}
+		case i == 0 && (arg == "serve" || arg == "mirror"):
+			{
+				continue
+			}
+		case i > 0 && valueFlags[args[i-1]]:
+			{
+				continue
+			}
+		case i > 0 && arg == replyTo:
+			{
+				// the comment to answer, taken by -reply
+				continue
+			}
+		}
+		rest = append(rest, arg)
+	}
+	return rest
 }
 
 func main() {
@@ -105,31 +423,104 @@ func main() {
 }
 
 func cli() error {
+	if serveMode {
+		return serve()
+	}
+	err := pbin.LoadDefaultConfig()
+	if err != nil {
+		return err
+	}
+	if configFile != "" {
+		err = pbin.LoadConfig(configFile)
+		if err != nil {
+			return err
+		}
+	}
+	if noPublic {
+		pbin.DisablePublicHosts()
+	}
+	if socksAddr != "" {
+		err = pbin.SetSOCKS5(socksAddr, "", "")
+		if err != nil {
+			return err
+		}
+	}
+	ctx := context.Background()
+	if timeout > 0 {
+		cancel := context.CancelFunc(nil)
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if bundleFile != "" {
+		err = readBundle(bundleFile)
+		if err != nil {
+			return err
+		}
+	}
 	switch {
+	case mirrorMode:
+		{
+			if getURL == nil {
+				return errors.New("missing paste url to mirror")
+			}
+			return mirror(ctx)
+		}
+	case deleteMode:
+		{
+			if getURL == nil {
+				return errors.New("usage: pbin -delete URL [TOKEN], missing paste url")
+			}
+			return del(ctx)
+		}
+	case getURL != nil && infoMode:
+		{
+			return info(ctx)
+		}
+	case getURL != nil && replyTo != "":
+		{
+			return comment(ctx)
+		}
 	case getURL != nil:
 		{
-			return get()
+			return get(ctx)
 		}
 	case getURL == nil:
 		{
-			return put()
+			return put(ctx)
 		}
 	}
 	return nil
 }
 
-func put() error {
-	info, err := os.Stdin.Stat()
-	if err != nil {
-		return err
-	}
-	if info.Mode()&os.ModeNamedPipe == 0 {
-		log.Fatalln("no pipe input, TODO print help")
+func put(ctx context.Context) error {
+	b := []byte{}
+	att := []byte(nil)
+	err := (error)(nil)
+	if attachFile != "" {
+		att, err = ioutil.ReadFile(attachFile)
+		if err != nil {
+			return err
+		}
+		if inFile != "" {
+			b, err = ioutil.ReadFile(inFile)
+		} else if hasPipe() {
+			b, err = ioutil.ReadAll(os.Stdin)
+		}
+	} else if inFile != "" {
+		b, err = ioutil.ReadFile(inFile)
+	} else {
+		b, err = readStdin()
 	}
-	b, err := ioutil.ReadAll(os.Stdin)
 	if err != nil {
 		return err
 	}
+	if format == "" {
+		// base64 text shows best as plain text, an explicit -format still wins
+		format = pbin.DetectFormat(inFile, b)
+		if base64Mode {
+			format = pbin.FormatPlainText
+		}
+	}
 	if base64Mode {
 		b = []byte(base64.StdEncoding.EncodeToString(b))
 	}
@@ -138,33 +529,322 @@ func put() error {
 	if err != nil {
 		return err
 	}
+	if att != nil {
+		p.SetAttachment(attachFile, att)
+	}
+	err = p.SetFormat(format)
+	if err != nil {
+		return err
+	}
 	p.BurnAfterRead(burnAfterRead)
 	p.OpenDiscussion(openDiscussion)
+	p.Shorten(shortenURL)
+	p.SetMaxAttempts(maxAttempts)
+	p.SetCopies(copies)
+	if hostURL != "" {
+		err = p.SetHost(hostURL)
+		if err != nil {
+			return err
+		}
+	}
 	if setExpiry != "" {
 		p.SetExpiry(setExpiry)
 	}
 	if password != "" {
 		p.SetPassword(password)
 	}
-	ur, _, err := p.Send()
+	r, err := p.SendContext(ctx)
+	return report(p, r, err)
+}
+
+// report prints where a sent paste went, the url on stdout and the rest on stderr
+func report(p *pbin.Paste, r *pbin.SendResult, err error) error {
+	if attempts := p.Attempts(); len(attempts) > 1 {
+		for _, a := range attempts {
+			if a.Err != nil {
+				fmt.Fprintln(os.Stderr, "failed:", a.Host, strings.SplitN(a.Err.Error(), "\n", 2)[0])
+			}
+		}
+	}
+	if r == nil {
+		return err
+	}
+	if jsonMode {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		jerr := enc.Encode(r)
+		if jerr != nil {
+			return jerr
+		}
+		return err
+	}
+	// one url per line with -copies, the output is a bundle for -bundle
+	for _, r := range append([]*pbin.SendResult{r}, r.Copies...) {
+		if r.ShortURL != "" {
+			fmt.Println(r.ShortURL)
+			fmt.Fprintln(os.Stderr, "full:", r.URL)
+		} else {
+			fmt.Println(r.URL)
+		}
+		if r.DeleteURL != "" {
+			fmt.Fprintln(os.Stderr, "delete:", r.DeleteURL)
+		}
+	}
+	return err
+}
+
+func mirror(ctx context.Context) error {
+	p, err := pbin.OpenPasteWithPromptContext(ctx, getURL, password, promptPassword)
+	if err != nil {
+		return err
+	}
+	if len(p.Comments()) > 0 {
+		fmt.Fprintln(os.Stderr, "comments are not mirrored:", len(p.Comments()))
+	}
+	m, err := p.Mirror(keepKey)
 	if err != nil {
 		return err
 	}
-	fmt.Println(ur)
+	m.Shorten(shortenURL)
+	m.SetMaxAttempts(maxAttempts)
+	if hostURL != "" {
+		err = m.SetHost(hostURL)
+		if err != nil {
+			return err
+		}
+	}
+	if setExpiry != "" {
+		m.SetExpiry(setExpiry)
+	}
+	r, err := m.SendContext(ctx)
+	return report(m, r, err)
+}
+
+func del(ctx context.Context) error {
+	if deleteToken == "" && getURL.Query().Get("deletetoken") == "" {
+		return errors.New("usage: pbin -delete URL TOKEN, or the delete url, missing delete token")
+	}
+	err := pbin.DeletePasteContext(ctx, getURL, deleteToken)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "paste deleted")
 	return nil
 }
 
-func get() error {
-	b, err := pbin.GetPaste(getURL)
+func info(ctx context.Context) error {
+	pi, err := pbin.InfoContext(ctx, getURL)
 	if err != nil {
 		return err
 	}
+	if jsonMode {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(pi)
+	}
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "id:\t%s\n", pi.PasteID)
+	fmt.Fprintf(w, "host:\t%s\n", pi.Host)
+	fmt.Fprintf(w, "version:\t%d\n", pi.Version)
+	if pi.Created != nil {
+		fmt.Fprintf(w, "created:\t%s\n", pi.Created.Format("2006-01-02 15:04:05"))
+	}
+	if pi.Expires == nil {
+		fmt.Fprintf(w, "expires:\tnever\n")
+	} else {
+		fmt.Fprintf(w, "expires:\t%s (in %s)\n", pi.Expires.Format("2006-01-02 15:04:05"), pi.TimeToLive().Round(time.Second))
+	}
+	fmt.Fprintf(w, "format:\t%s\n", pi.Format)
+	fmt.Fprintf(w, "burn after reading:\t%s\n", yesNo(pi.BurnAfterReading))
+	fmt.Fprintf(w, "discussion:\t%s\n", yesNo(pi.OpenDiscussion))
+	if pi.NeedsPassword && pi.Version != 1 {
+		fmt.Fprintf(w, "attachment:\tunknown\n")
+	} else {
+		fmt.Fprintf(w, "attachment:\t%s\n", yesNo(pi.Attachment))
+	}
+	fmt.Fprintf(w, "comments:\t%d\n", pi.Comments)
+	fmt.Fprintf(w, "password:\t%s\n", yesNo(pi.NeedsPassword))
+	fmt.Fprintf(w, "size:\t%d bytes\n", pi.CipherSize)
+	return w.Flush()
+}
+
+func comment(ctx context.Context) error {
+	b, err := readStdin()
+	if err != nil {
+		return err
+	}
+	c, err := pbin.CraftComment(getURL, b)
+	if err != nil {
+		return err
+	}
+	if replyTo != "parent" {
+		c.ReplyTo(replyTo)
+	}
+	if nickname != "" {
+		c.SetNickname(nickname)
+	}
+	if password != "" {
+		c.SetPassword(password)
+	}
+	id, err := c.SendContext(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Println(id)
+	return nil
+}
+
+func serve() error {
+	st := server.Storage(nil)
+	if phpDataDir != "" && dataDir != "" {
+		return errors.New("-data and -privatebin are mutually exclusive")
+	}
+	if phpDataDir != "" {
+		pb, err := server.NewPrivateBinDir(phpDataDir)
+		if err != nil {
+			return err
+		}
+		st = pb
+	} else if dataDir != "" {
+		fs, err := server.NewFilesystem(dataDir)
+		if err != nil {
+			return err
+		}
+		st = fs
+	} else {
+		fmt.Fprintln(os.Stderr, "no -data dir, pastes are kept in memory and lost on exit")
+		st = server.NewMemory()
+	}
+	srv := server.New(st)
+	if p := strings.Trim(servePrefix, "/"); p != "" {
+		srv.Prefix = "/" + p + "/"
+	}
+	srv.MaxSize = maxSize
+	srv.MaxExpiry = maxExpiry
+	srv.RateLimit = rateLimit
+	srv.IPHeader = ipHeader
+	if purgeEvery > 0 {
+		go srv.RunPurger(context.Background(), purgeEvery, log.Printf)
+	}
+	hs := &http.Server{
+		Addr:              listenAddr,
+		Handler:           srv,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if certFile != "" || keyFile != "" {
+		fmt.Fprintln(os.Stderr, "serving on https://"+listenAddr+srv.Prefix)
+		return hs.ListenAndServeTLS(certFile, keyFile)
+	}
+	fmt.Fprintln(os.Stderr, "serving on http://"+listenAddr+srv.Prefix)
+	return hs.ListenAndServe()
+}
+
+// parseSize reads a byte count with an optional K, M or G suffix, in powers of 1024
+func parseSize(v string) (int64, error) {
+	shift := uint(0)
+	u := strings.ToUpper(v)
+	u = strings.TrimSuffix(u, "B")
+	u = strings.TrimSuffix(u, "I")
+	switch {
+	case strings.HasSuffix(u, "K"):
+		{
+			shift = 10
+		}
+	case strings.HasSuffix(u, "M"):
+		{
+			shift = 20
+		}
+	case strings.HasSuffix(u, "G"):
+		{
+			shift = 30
+		}
+	}
+	if shift > 0 {
+		u = u[:len(u)-1]
+	}
+	n, err := strconv.ParseInt(u, 10, 64)
+	if err != nil || n < 0 {
+		return 0, errors.New("invalid size: " + v)
+	}
+	return n << shift, nil
+}
+
+func readStdin() ([]byte, error) {
+	if !hasPipe() {
+		log.Fatalln("no pipe input, TODO print help")
+	}
+	return ioutil.ReadAll(os.Stdin)
+}
+
+func hasPipe() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeNamedPipe != 0
+}
+
+// readBundle adds the urls of a bundle file, one per line, - reads stdin
+func readBundle(name string) error {
+	b := []byte(nil)
+	err := error(nil)
+	if name == "-" {
+		b, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		b, err = ioutil.ReadFile(name)
+	}
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Fields(string(b)) {
+		u, err := url.Parse(line)
+		if err != nil {
+			return err
+		}
+		bundleURLs = append(bundleURLs, u)
+	}
+	if getURL == nil && len(bundleURLs) > 0 {
+		getURL = bundleURLs[0]
+	}
+	return nil
+}
+
+func get(ctx context.Context) error {
+	p, err := (*pbin.Paste)(nil), error(nil)
+	if len(bundleURLs) > 1 {
+		p, err = pbin.OpenBundleWithPromptContext(ctx, bundleURLs, password, promptPassword)
+	} else {
+		p, err = pbin.OpenPasteWithPromptContext(ctx, getURL, password, promptPassword)
+	}
+	if err != nil {
+		return err
+	}
+	b := p.Text()
 	if base64Mode {
 		b, err = base64.StdEncoding.DecodeString(string(b))
 		if err != nil {
 			return err
 		}
 	}
+	if name, att := p.Attachment(); att != nil {
+		// the attachment takes the output file, the text still goes to stdout
+		if outFile != "" {
+			err = ioutil.WriteFile(outFile, att, 0644)
+		} else {
+			outFile, err = saveAttachment(name, att)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "attachment saved to", outFile)
+		outFile = ""
+	}
 	if outFile != "" {
 		err = ioutil.WriteFile(outFile, b, 0644)
 		if err != nil {
@@ -176,5 +856,78 @@ func get() error {
 			return err
 		}
 	}
+	if showThread {
+		printThread(os.Stdout, p.Comments())
+	}
 	return nil
 }
+
+// saveAttachment writes b to the current directory under the name the paste author chose,
+// only plain names are taken and an existing file is never replaced, -o overrides both
+func saveAttachment(name string, b []byte) (string, error) {
+	base := filepath.Base(name)
+	if name == "" || base != name || strings.HasPrefix(base, ".") || strings.ContainsAny(base, `/\`) {
+		return "", errors.New("unsafe attachment name " + strconv.Quote(name) + ", save it with -o FILE")
+	}
+	f, err := os.OpenFile(base, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return "", errors.New("attachment " + base + " already exists, save it with -o FILE")
+	}
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(b)
+	if err != nil {
+		f.Close()
+		os.Remove(base)
+		return "", err
+	}
+	return base, f.Close()
+}
+
+func promptPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errors.New("paste needs a password, use -password")
+	}
+	fmt.Fprint(os.Stderr, "password: ")
+	b, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func printThread(w io.Writer, comments []*pbin.Comment) {
+	children := map[string][]*pbin.Comment{}
+	ids := map[string]bool{}
+	for _, c := range comments {
+		ids[c.ID()] = true
+	}
+	roots := []*pbin.Comment{}
+	for _, c := range comments {
+		if ids[c.ParentID()] {
+			children[c.ParentID()] = append(children[c.ParentID()], c)
+		} else {
+			roots = append(roots, c)
+		}
+	}
+	fmt.Fprintf(w, "\n--- %d comment(s)\n", len(comments))
+	var walk func(cs []*pbin.Comment, depth int)
+	walk = func(cs []*pbin.Comment, depth int) {
+		for _, c := range cs {
+			indent := strings.Repeat("    ", depth)
+			nick := c.Nickname()
+			if nick == "" {
+				nick = "anonymous"
+			}
+			fmt.Fprintf(w, "%s%s [%s] (%s):\n", indent, nick, c.PostDate().Format("2006-01-02 15:04:05"), c.ID())
+			for _, line := range strings.Split(string(c.Text()), "\n") {
+				fmt.Fprintf(w, "%s  %s\n", indent, line)
+			}
+			walk(children[c.ID()], depth+1)
+		}
+	}
+	walk(roots, 0)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cbluth/pbin/pbintest"
+)
+
+func TestPositional(t *testing.T) {
+	u := "https://paste.example.org/?0011223344556677#key"
+	for _, tc := range []struct {
+		args string
+		want []string
+	}{
+		{"-delete " + u + " a1b2", []string{"a1b2"}},
+		{u + " -delete a1b2", []string{"a1b2"}},
+		{"-delete -json " + u + " a1b2", []string{"a1b2"}},
+		{"-delete a1b2 " + u, []string{"a1b2"}},
+		{"-host https://other.example.org/ -delete " + u + " -timeout 5s a1b2", []string{"a1b2"}},
+		{"-delete " + u, []string{}},
+		{"mirror " + u + " -expire day", []string{}},
+	} {
+		got := positional(strings.Fields(tc.args))
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %q, want %q", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestPrintThread(t *testing.T) {
+	s := pbintest.NewServer()
+	defer s.Close()
+	c := s.Client()
+	p, _ := c.CraftPaste([]byte("discuss"))
+	p.OpenDiscussion(true)
+	r, err := p.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := r.PasteURL()
+	send := func(text, parent, nick string) string {
+		cm, _ := c.CraftComment(u, []byte(text))
+		cm.ReplyTo(parent)
+		cm.SetNickname(nick)
+		id, err := cm.Send()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	first := send("first", "", "ann")
+	send("two\nlines", first, "")
+	got, err := c.OpenPaste(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &bytes.Buffer{}
+	printThread(b, got.Comments())
+	out := b.String()
+	// a reply is indented under its parent, a comment without a nickname is anonymous
+	for _, want := range []string{"--- 2 comment(s)\n", "\nann [", "\n  first\n", "\n    anonymous [", "\n      two\n      lines\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("%q not in\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "ann [") > strings.Index(out, "anonymous [") {
+		t.Errorf("reply printed before its parent\n%s", out)
+	}
+}
+
+func TestSaveAttachment(t *testing.T) {
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(t.TempDir())
+	for _, name := range []string{"", "../up.txt", "/etc/passwd", "dir/file.txt", ".bashrc", `a\b.txt`} {
+		if _, err := saveAttachment(name, []byte("x")); err == nil {
+			t.Errorf("%q: saved", name)
+		}
+	}
+	name, err := saveAttachment("report.txt", []byte("first"))
+	if err != nil || name != "report.txt" {
+		t.Fatalf("got %q, %v", name, err)
+	}
+	// an existing file is kept
+	if _, err = saveAttachment("report.txt", []byte("second")); err == nil {
+		t.Error("replaced an existing file")
+	}
+	if b, _ := ioutil.ReadFile("report.txt"); string(b) != "first" {
+		t.Errorf("file has %q", b)
+	}
+}
@@ -0,0 +1,241 @@
+package pbin
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gearnode/base58"
+)
+
+type (
+	Comment struct {
+		//
+		client         *Client
+		id             string
+		pasteid        string
+		parentid       string
+		hostAPI        *url.URL
+		clearTextData  []byte
+		cipherJSONData []byte
+		urlSecret      []byte
+		salt           [SaltSize]byte
+		nonce          [NonceSize]byte // IV
+		nickname       string
+		userPassword   string
+		postdate       time.Time
+		version        int    // 1 for comments on v1 pastes
+		key            string // the base64 key of a v1 paste
+	}
+)
+
+// CraftComment prepares a reply to the paste at ur,
+// the url must carry the paste secret in its fragment
+func CraftComment(ur *url.URL, b []byte) (*Comment, error) {
+	return DefaultClient.CraftComment(ur, b)
+}
+
+// CraftComment prepares a reply sent through the client
+func (cl *Client) CraftComment(ur *url.URL, b []byte) (*Comment, error) {
+	hostAPI, pID, key, err := splitPasteURL(ur)
+	if err != nil {
+		return nil, err
+	}
+	c := &Comment{
+		client:        cl,
+		pasteid:       pID,
+		parentid:      pID,
+		hostAPI:       hostAPI,
+		clearTextData: b,
+	}
+	// a v1 key is base64, which is rarely valid base58 of the right size
+	secret, err := decodeKey(key)
+	h := cl.hosts.getHost(hostAPI.String())
+	if err != nil || len(secret) != KDFSecretSize || (h != nil && h.version == 1) {
+		c.version = 1
+		c.key = key
+	}
+	c.urlSecret = secret
+	copy(c.salt[:], randomBytes(SaltSize))
+	copy(c.nonce[:], randomBytes(NonceSize)) // IV
+	return c, nil
+}
+
+// ReplyTo sets the comment to answer, by default a comment replies to the paste itself
+func (c *Comment) ReplyTo(parentID string) {
+	if parentID != "" {
+		c.parentid = parentID
+	}
+}
+
+func (c *Comment) SetNickname(nickname string) {
+	c.nickname = nickname
+}
+
+// SetPassword must match the password of the paste being commented on
+func (c *Comment) SetPassword(pass string) {
+	c.userPassword = pass
+}
+
+// Send posts the comment and returns the id the server gave it, ID returns it as well
+func (c *Comment) Send() (string, error) {
+	return c.SendContext(context.Background())
+}
+
+// SendContext is Send with a context for the request
+func (c *Comment) SendContext(ctx context.Context) (string, error) {
+	cl := c.client
+	if cl == nil {
+		cl = DefaultClient
+	}
+	resm := map[string]interface{}(nil)
+	if c.version == 1 {
+		form, err := c.legacyForm()
+		if err != nil {
+			return "", err
+		}
+		resm, err = cl.postForm(ctx, c.hostAPI, form)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		err := c.encrypt()
+		if err != nil {
+			return "", err
+		}
+		reqb := map[string]interface{}{}
+		reqb["v"] = PrivateBinAPIVersion
+		reqb["adata"] = makeCipherSpec(c.nonce[:], c.salt[:])
+		reqb["meta"] = map[string]interface{}{}
+		reqb["ct"] = base64.RawStdEncoding.EncodeToString(c.cipherJSONData)
+		reqb["pasteid"] = c.pasteid
+		reqb["parentid"] = c.parentid
+		requestBodyJSONData, err := json.Marshal(&reqb)
+		if err != nil {
+			return "", err
+		}
+		resm, err = cl.postJSON(ctx, c.hostAPI, requestBodyJSONData)
+		if err != nil {
+			return "", err
+		}
+	}
+	id, ok := resm["id"].(string)
+	if !ok {
+		return "", &ServerError{Host: c.hostAPI.String(), StatusCode: http.StatusOK, Kind: ErrRejected, Cause: errors.New("missing comment id in response")}
+	}
+	c.id = id
+	return id, nil
+}
+
+func (c *Comment) encrypt() error {
+	clear := map[string]interface{}{
+		"comment": string(c.clearTextData),
+	}
+	if c.nickname != "" {
+		clear["nickname"] = c.nickname
+	}
+	clearJSONData, err := json.Marshal(&clear)
+	if err != nil {
+		return err
+	}
+	// comments carry only the cipher spec as adata, not the paste options
+	c.cipherJSONData, err = sealJSON(
+		deriveKey(c.urlSecret, c.userPassword, c.salt[:]),
+		c.nonce[:],
+		clearJSONData,
+		makeCipherSpec(c.nonce[:], c.salt[:]),
+	)
+	return err
+}
+
+func (c *Comment) ID() string {
+	return c.id
+}
+
+func (c *Comment) PasteID() string {
+	return c.pasteid
+}
+
+// ParentID is the paste id for top level comments
+func (c *Comment) ParentID() string {
+	return c.parentid
+}
+
+func (c *Comment) Nickname() string {
+	return c.nickname
+}
+
+func (c *Comment) PostDate() time.Time {
+	return c.postdate
+}
+
+func (c *Comment) Text() []byte {
+	return c.clearTextData
+}
+
+func openComment(hostAPI *url.URL, secret []byte, pass string, cm map[string]interface{}) (*Comment, error) {
+	spec, ok := cm["adata"].([]interface{})
+	if !ok {
+		return nil, ErrInvalidPaste
+	}
+	cd, err := openJSON(secret, pass, cm["ct"], spec, spec)
+	if err != nil {
+		return nil, err
+	}
+	c := &Comment{
+		hostAPI:      hostAPI,
+		urlSecret:    secret,
+		userPassword: pass,
+	}
+	c.id, _ = cm["id"].(string)
+	c.pasteid, _ = cm["pasteid"].(string)
+	c.parentid, _ = cm["parentid"].(string)
+	if v, ok := cd["comment"].(string); ok {
+		c.clearTextData = []byte(v)
+	}
+	c.nickname, _ = cd["nickname"].(string)
+	if meta, ok := cm["meta"].(map[string]interface{}); ok {
+		// older servers call it postdate, newer ones created
+		for _, k := range []string{"created", "postdate"} {
+			if ts, ok := meta[k].(float64); ok {
+				c.postdate = time.Unix(int64(ts), 0)
+				break
+			}
+		}
+	}
+	return c, nil
+}
+
+// splitPasteURL returns the key as found in the fragment,
+// base58 for v2 pastes and base64 for v1 ones
+func splitPasteURL(ur *url.URL) (*url.URL, string, string, error) {
+	pID := ur.RawQuery
+	if strings.HasPrefix(pID, "pasteid=") {
+		pID = strings.TrimPrefix(pID, "pasteid=")
+	}
+	if pID == "" {
+		return nil, "", "", fmt.Errorf("%w: missing paste id", ErrInvalidURL)
+	}
+	if ur.Fragment == "" {
+		return nil, "", "", fmt.Errorf("%w: missing paste key", ErrInvalidURL)
+	}
+	hostAPI, err := url.Parse(strings.Split(ur.String(), "?")[0])
+	if err != nil {
+		return nil, "", "", err
+	}
+	return hostAPI, pID, ur.Fragment, nil
+}
+
+func decodeKey(key string) ([]byte, error) {
+	secret, err := base58.Decode(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: paste key is not base58", ErrInvalidURL)
+	}
+	return secret, nil
+}
@@ -0,0 +1,95 @@
+package pbin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/gearnode/base58"
+)
+
+func TestComments(t *testing.T) {
+	pings := int32(0)
+	c := copiesClient(t, &pings)
+	h := newTestHost(t)
+	addTestHost(t, c, h, false)
+	p, _ := c.CraftPaste([]byte("discuss"))
+	p.OpenDiscussion(true)
+	p.SetPassword("pw")
+	r, err := p.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := r.PasteURL()
+	cm, _ := c.CraftComment(u, []byte("first"))
+	cm.SetNickname("ann")
+	cm.SetPassword("pw")
+	first, err := cm.Send()
+	if err != nil || first == "" || cm.ID() != first {
+		t.Fatalf("got %q, %v", first, err)
+	}
+	reply, _ := c.CraftComment(u, []byte("second"))
+	reply.SetPassword("pw")
+	reply.ReplyTo(first)
+	if _, err = reply.Send(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.OpenPasteWithPromptContext(context.Background(), u, "pw", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := got.Comments()
+	byText := map[string]*Comment{}
+	for _, x := range cs {
+		byText[string(x.Text())] = x
+	}
+	if len(cs) != 2 || byText["first"] == nil || byText["second"] == nil {
+		t.Fatalf("comments %v", cs)
+	}
+	if a := byText["first"]; a.Nickname() != "ann" || a.ParentID() != r.PasteID || a.PasteID() != r.PasteID || a.PostDate().IsZero() {
+		t.Errorf("first comment %+v", a)
+	}
+	if b := byText["second"]; b.ParentID() != first || b.Nickname() != "" {
+		t.Errorf("reply %+v", b)
+	}
+}
+
+func TestCommentWithoutDiscussion(t *testing.T) {
+	pings := int32(0)
+	c := copiesClient(t, &pings)
+	h := newTestHost(t)
+	addTestHost(t, c, h, false)
+	p, _ := c.CraftPaste([]byte("quiet"))
+	r, err := p.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := r.PasteURL()
+	cm, _ := c.CraftComment(u, []byte("hello"))
+	if _, err = cm.Send(); !errors.Is(err, ErrNoDiscussion) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestCraftCommentVersion(t *testing.T) {
+	c := NewClient(nil)
+	// a v2 key is base58 of the whole secret, a v1 key base64
+	for key, version := range map[string]int{
+		base58.Encode(bytes.Repeat([]byte{7}, KDFSecretSize)): 0,
+		"dGhpcyBpcyBhIHYxIGtleSBvZiAzMiBieXRlcyEhISE=":        1,
+	} {
+		u, _ := url.Parse("https://p.example.org/?0011223344556677#" + key)
+		cm, err := c.CraftComment(u, []byte("hi"))
+		if err != nil || cm.version != version {
+			t.Errorf("%s: version %d, %v", key, cm.version, err)
+		}
+	}
+	for _, s := range []string{"https://p.example.org/#key", "https://p.example.org/?0011223344556677"} {
+		u, _ := url.Parse(s)
+		if _, err := c.CraftComment(u, []byte("hi")); !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("%s: got %v", s, err)
+		}
+	}
+}
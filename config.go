@@ -0,0 +1,155 @@
+package pbin
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+type (
+	// Config is the json host registry file, for example:
+	//
+	//	{
+	//		"public": false,
+	//		"hosts": [
+	//			{
+	//				"api": "https://privatebin.example.com/",
+	//				"expiry": ["1hour", "1day", "1week"],
+	//				"features": ["burn", "discussion", "upload file"]
+	//			}
+	//		]
+	//	}
+	//
+	// A host without "expiry" takes every expiry and one without "features" takes
+	// burn and discussion, like a host given to SetHost. An empty "expiry" list is an error
+	Config struct {
+		Public  *bool        `json:"public,omitempty"`  // false turns off the public directory
+		Replace bool         `json:"replace,omitempty"` // drop the hosts known before this file
+		Hosts   []ConfigHost `json:"hosts"`
+	}
+	ConfigHost struct {
+		API       string   `json:"api"`
+		Expiry    []string `json:"expiry"`
+		Features  []string `json:"features"`
+		Shortener string   `json:"shortener,omitempty"` // the paste url is appended
+		Version   int      `json:"version,omitempty"`   // 1 for zerobin and privatebin before 1.3
+	}
+)
+
+// ConfigPaths are read by LoadDefaultConfig, the system file first
+func ConfigPaths() []string {
+	paths := []string{filepath.Join("/etc", "pbin", "hosts.json")}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "pbin", "hosts.json"))
+	}
+	if p := os.Getenv("PBIN_CONFIG"); p != "" {
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// LoadDefaultConfig applies every file of ConfigPaths that exists
+func LoadDefaultConfig() error {
+	return DefaultClient.LoadDefaultConfig()
+}
+
+func (c *Client) LoadDefaultConfig() error {
+	for _, p := range ConfigPaths() {
+		err := c.LoadConfig(p)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadConfig merges the hosts of the file at path into the registry,
+// a host already known under the same url is replaced by the file entry
+func LoadConfig(path string) error {
+	return DefaultClient.LoadConfig(path)
+}
+
+func (c *Client) LoadConfig(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	cfg := Config{}
+	err = json.Unmarshal(b, &cfg)
+	if err != nil {
+		return errors.New("config " + path + ": " + err.Error())
+	}
+	err = c.ApplyConfig(&cfg)
+	if err != nil {
+		return errors.New("config " + path + ": " + err.Error())
+	}
+	return nil
+}
+
+// Apply changes the registry of the default client
+func (cfg *Config) Apply() error {
+	return DefaultClient.ApplyConfig(cfg)
+}
+
+// ApplyConfig validates every host before touching the registry
+func (c *Client) ApplyConfig(cfg *Config) error {
+	hsts := []*host{}
+	for _, ch := range cfg.Hosts {
+		u, err := parseHostURL(ch.API)
+		if err != nil {
+			return err
+		}
+		if ch.Version != 0 && ch.Version != 1 && ch.Version != PrivateBinAPIVersion {
+			return errors.New("host " + ch.API + ": unknown api version " + strconv.Itoa(ch.Version))
+		}
+		h := &host{api: u, shortener: ch.Shortener, version: ch.Version}
+		switch {
+		case ch.Expiry == nil:
+			{
+				h.expiry = []Expiry{Hour, Day, Week, Month, Year, Never}
+			}
+		case len(ch.Expiry) == 0:
+			{
+				// it would never be picked
+				return errors.New("host " + ch.API + ": no expiry")
+			}
+		}
+		if ch.Features == nil {
+			h.features = []Feature{Burn, Discussion}
+		}
+		for _, es := range ch.Expiry {
+			ex, err := ParseExpiry(es)
+			if err != nil {
+				return err
+			}
+			h.expiry = append(h.expiry, ex)
+		}
+		for _, fs := range ch.Features {
+			f, err := ParseFeature(fs)
+			if err != nil {
+				return err
+			}
+			h.features = append(h.features, f)
+		}
+		hsts = append(hsts, h)
+	}
+	if cfg.Public != nil && !*cfg.Public {
+		c.DisablePublicHosts()
+	}
+	if cfg.Replace {
+		c.hosts.removeHosts(func(h *host) bool {
+			return true
+		})
+	}
+	for _, h := range hsts {
+		api := h.api.String()
+		c.hosts.removeHosts(func(hh *host) bool {
+			return hh.api.String() == api
+		})
+		c.hosts.addHost(h)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package pbin
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func loadTestConfig(t *testing.T, c *Client, json string) error {
+	path := filepath.Join(t.TempDir(), "hosts.json")
+	err := ioutil.WriteFile(path, []byte(json), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.LoadConfig(path)
+}
+
+func TestLoadConfig(t *testing.T) {
+	c := NewClient(nil)
+	err := loadTestConfig(t, c, `{
+		"public": false,
+		"hosts": [
+			{"api": "https://a.example.org/", "expiry": ["1day"], "features": ["upload file"], "version": 1},
+			{"api": "https://b.example.org/"}
+		]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.hosts.hosts) != 2 {
+		t.Fatalf("%d hosts, the public ones should be gone", len(c.hosts.hosts))
+	}
+	a := c.hosts.getHost("https://a.example.org/")
+	if a == nil || a.version != 1 || !a.hasExpiry(Day) || a.hasExpiry(Week) || !a.hasFeature(UploadFile) || a.hasFeature(Burn) {
+		t.Errorf("host a %+v", a)
+	}
+	// left out expiry and features get the defaults of SetHost
+	b := c.hosts.getHost("https://b.example.org/")
+	if b == nil || !b.hasExpiry(Never) || !b.hasExpiry(Hour) || !b.hasFeature(Burn) || !b.hasFeature(Discussion) {
+		t.Fatalf("host b %+v", b)
+	}
+	if len(c.hosts.filterHosts(Week, []Feature{Discussion})) != 1 {
+		t.Error("host b is not picked")
+	}
+	// a later file replaces a host with the same url
+	err = loadTestConfig(t, c, `{"hosts": [{"api": "https://b.example.org/", "expiry": ["1hour"], "features": []}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = c.hosts.getHost("https://b.example.org/")
+	if len(c.hosts.hosts) != 2 || b.hasExpiry(Never) || b.hasFeature(Burn) {
+		t.Errorf("host b %+v", b)
+	}
+	err = loadTestConfig(t, c, `{"replace": true, "hosts": [{"api": "https://c.example.org/"}]}`)
+	if err != nil || len(c.hosts.hosts) != 1 || c.hosts.getHost("https://c.example.org/") == nil {
+		t.Errorf("replace left %d hosts, %v", len(c.hosts.hosts), err)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	for _, tc := range []struct {
+		json, want string
+	}{
+		{`{"hosts": [{"api": "https://a.example.org/", "expiry": []}]}`, "no expiry"},
+		{`{"hosts": [{"api": "https://a.example.org/", "expiry": ["1fortnight"]}]}`, "1fortnight"},
+		{`{"hosts": [{"api": "https://a.example.org/", "features": ["teleport"]}]}`, "teleport"},
+		{`{"hosts": [{"api": "https://a.example.org/", "version": 3}]}`, "api version"},
+		{`{"hosts": [{"api": "ftp://a.example.org/"}]}`, ""},
+		{`{"hosts": [`, ""},
+	} {
+		c := NewClient(nil)
+		before := len(c.hosts.hosts)
+		err := loadTestConfig(t, c, tc.json)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want %q", tc.json, err, tc.want)
+		}
+		if len(c.hosts.hosts) != before {
+			t.Errorf("%s: registry changed", tc.json)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package pbin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DeletePaste removes the paste at ur with the deletetoken the server returned from Send,
+// ur may also be the delete link itself, in which case token can be empty
+func DeletePaste(ur *url.URL, token string) error {
+	return DefaultClient.DeletePasteContext(context.Background(), ur, token)
+}
+
+// DeletePasteContext is DeletePaste with a context for the request
+func DeletePasteContext(ctx context.Context, ur *url.URL, token string) error {
+	return DefaultClient.DeletePasteContext(ctx, ur, token)
+}
+
+func (c *Client) DeletePaste(ur *url.URL, token string) error {
+	return c.DeletePasteContext(context.Background(), ur, token)
+}
+
+func (c *Client) DeletePasteContext(ctx context.Context, ur *url.URL, token string) error {
+	hostURL, pID, tk := splitDeleteURL(ur)
+	if token == "" {
+		token = tk
+	}
+	if token == "" {
+		return errors.New("missing delete token")
+	}
+	if pID == "" {
+		return fmt.Errorf("%w: missing paste id", ErrInvalidURL)
+	}
+	_, err := c.getJSON(ctx, makeDeleteURL(hostURL, pID, token))
+	return err
+}
+
+// DeleteURL is the link the privatebin web ui shows after creating a paste
+func DeleteURL(ur *url.URL, token string) (*url.URL, error) {
+	hostURL, pID, _ := splitDeleteURL(ur)
+	if pID == "" {
+		return nil, fmt.Errorf("%w: missing paste id", ErrInvalidURL)
+	}
+	return url.Parse(makeDeleteURL(hostURL, pID, token))
+}
+
+func splitDeleteURL(ur *url.URL) (string, string, string) {
+	hostURL := strings.Split(ur.String(), "?")[0]
+	q, _ := url.ParseQuery(ur.RawQuery)
+	pID := q.Get("pasteid")
+	if pID == "" && !strings.ContainsAny(ur.RawQuery, "=&") {
+		pID = ur.RawQuery
+	}
+	return hostURL, pID, q.Get("deletetoken")
+}
+
+func makeDeleteURL(hostURL string, pID string, token string) string {
+	return hostURL + "?pasteid=" + url.QueryEscape(pID) + "&deletetoken=" + url.QueryEscape(token)
+}
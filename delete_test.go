@@ -0,0 +1,61 @@
+package pbin
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestSplitDeleteURL(t *testing.T) {
+	for _, tc := range []struct {
+		in, host, id, token string
+	}{
+		{"https://p.example.org/?0011223344556677#key", "https://p.example.org/", "0011223344556677", ""},
+		{"https://p.example.org/?pasteid=0011223344556677&deletetoken=a1b2", "https://p.example.org/", "0011223344556677", "a1b2"},
+		{"https://p.example.org/sub/?pasteid=0011223344556677", "https://p.example.org/sub/", "0011223344556677", ""},
+		{"https://p.example.org/?x=1", "https://p.example.org/", "", ""},
+	} {
+		u, _ := url.Parse(tc.in)
+		host, id, token := splitDeleteURL(u)
+		if host != tc.host || id != tc.id || token != tc.token {
+			t.Errorf("%s: got %s %s %s", tc.in, host, id, token)
+		}
+	}
+}
+
+func TestDeletePaste(t *testing.T) {
+	pings := int32(0)
+	c := copiesClient(t, &pings)
+	h := newTestHost(t)
+	addTestHost(t, c, h, false)
+	for _, byLink := range []bool{false, true} {
+		p, _ := c.CraftPaste([]byte("gone soon"))
+		r, err := p.Send()
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, _ := r.PasteURL()
+		if err = c.DeletePaste(u, ""); err == nil {
+			t.Error("deleted without a token")
+		}
+		if err = c.DeletePaste(u, "wrong"); err == nil {
+			t.Error("deleted with a wrong token")
+		}
+		if byLink {
+			// the delete link alone carries the id and the token
+			d, _ := url.Parse(r.DeleteURL)
+			err = c.DeletePaste(d, "")
+		} else {
+			err = c.DeletePaste(u, r.DeleteToken)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = c.GetPaste(u); !errors.Is(err, ErrNotFound) {
+			t.Errorf("after delete got %v", err)
+		}
+	}
+	if h.pastes(t) != 0 {
+		t.Errorf("%d pastes left", h.pastes(t))
+	}
+}
@@ -0,0 +1,153 @@
+package pbin
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var (
+	// privatebin does not flag password protected pastes,
+	// a missing password looks the same as a wrong key
+	ErrDecrypt = errors.New("unable to decrypt paste, wrong key or password")
+	// ErrWrongKey and ErrWrongPassword match ErrDecrypt with errors.Is
+	ErrWrongKey      error = &decryptError{"unable to decrypt paste, wrong key or a password is needed"}
+	ErrWrongPassword error = &decryptError{"unable to decrypt paste, wrong password"}
+
+	ErrInvalidURL   = errors.New("invalid paste url")
+	ErrInvalidPaste = errors.New("invalid paste data")
+	ErrNoHost       = errors.New("no reachable host allows the requested expiry and features")
+	ErrNotFound     = errors.New("paste does not exist, has expired or has been deleted")
+	ErrRateLimited  = errors.New("rate limited by host")
+	ErrTooLarge     = errors.New("paste is too large for host")
+	ErrWrongToken   = errors.New("wrong delete token")
+	ErrNoDiscussion = errors.New("discussion is disabled")
+	ErrRejected     = errors.New("host rejected the request")
+	ErrHostDown     = errors.New("host unreachable or failing")
+	ErrShortener    = errors.New("url shortener failed")
+
+	errNotJSON = errors.New("response is not json")
+)
+
+type (
+	// ServerError is a failed exchange with a host, match it with errors.Is
+	// against ErrNotFound, ErrRateLimited, ErrTooLarge, ErrWrongToken,
+	// ErrNoDiscussion, ErrRejected, ErrHostDown or ErrShortener
+	ServerError struct {
+		Host       string
+		StatusCode int    // http status, 0 when the request did not complete
+		Message    string // message of a privatebin status 1 reply
+		Kind       error  // one of the sentinels above
+		Cause      error  // network or decoding error, may be nil
+	}
+	decryptError struct {
+		msg string
+	}
+)
+
+func (e *ServerError) Error() string {
+	s := e.Host + ": " + e.Kind.Error()
+	if e.Message != "" {
+		// the privatebin message says it better
+		s = e.Host + ": " + e.Message
+	}
+	if e.StatusCode != 0 && e.StatusCode != http.StatusOK {
+		s += " (http " + strconv.Itoa(e.StatusCode) + ")"
+	}
+	if e.Cause != nil {
+		s += ": " + e.Cause.Error()
+	}
+	return s
+}
+
+func (e *ServerError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *ServerError) Unwrap() error {
+	return e.Cause
+}
+
+// failover is true when another host may take what this one refused
+func (e *ServerError) failover() bool {
+	return e.Kind == ErrHostDown || e.Message != ""
+}
+
+func (e *decryptError) Error() string {
+	return e.msg
+}
+
+func (e *decryptError) Is(target error) bool {
+	return target == ErrDecrypt
+}
+
+// stripURL drops the request url net/http puts in its errors,
+// it can hold the paste key or the delete token
+func stripURL(err error) error {
+	ue := (*url.Error)(nil)
+	if errors.As(err, &ue) {
+		return ue.Err
+	}
+	return err
+}
+
+func isFailover(err error) bool {
+	se := (*ServerError)(nil)
+	return errors.As(err, &se) && se.failover()
+}
+
+// statusError maps an http status without a privatebin reply
+func statusError(host string, code int) *ServerError {
+	e := &ServerError{Host: host, StatusCode: code, Kind: ErrRejected}
+	switch {
+	case code == http.StatusNotFound:
+		{
+			e.Kind = ErrNotFound
+		}
+	case code == http.StatusTooManyRequests:
+		{
+			e.Kind = ErrRateLimited
+		}
+	case code == http.StatusRequestEntityTooLarge:
+		{
+			e.Kind = ErrTooLarge
+		}
+	case code >= 500:
+		{
+			e.Kind = ErrHostDown
+		}
+	}
+	return e
+}
+
+// messageError maps the message of a privatebin status 1 reply,
+// the messages are the english ones of the privatebin php code
+func messageError(host string, code int, msg string) *ServerError {
+	e := &ServerError{Host: host, StatusCode: code, Message: msg, Kind: ErrRejected}
+	m := strings.ToLower(msg)
+	switch {
+	case strings.Contains(m, "does not exist") || strings.Contains(m, "expired"):
+		{
+			e.Kind = ErrNotFound
+		}
+	case strings.Contains(m, "please wait") || strings.Contains(m, "traffic"):
+		{
+			e.Kind = ErrRateLimited
+		}
+	case strings.Contains(m, "limited to"):
+		{
+			e.Kind = ErrTooLarge
+		}
+	case strings.Contains(m, "deletion token"):
+		{
+			e.Kind = ErrWrongToken
+		}
+	case strings.Contains(m, "discussion is disabled"):
+		{
+			e.Kind = ErrNoDiscussion
+		}
+	}
+	return e
+}
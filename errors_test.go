@@ -0,0 +1,68 @@
+package pbin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDecodeReplyKinds(t *testing.T) {
+	for _, tc := range []struct {
+		code int
+		body string
+		want error
+	}{
+		{http.StatusNotFound, "not found", ErrNotFound},
+		{http.StatusTooManyRequests, "", ErrRateLimited},
+		{http.StatusRequestEntityTooLarge, "", ErrTooLarge},
+		{http.StatusBadGateway, "<html>", ErrHostDown},
+		{http.StatusForbidden, "", ErrRejected},
+		// a 200 that is not json is a broken host, not a refusal
+		{http.StatusOK, "<html>captive portal</html>", ErrHostDown},
+		{http.StatusOK, `{"status":1,"message":"Paste does not exist, has expired or has been deleted."}`, ErrNotFound},
+		{http.StatusOK, `{"status":1,"message":"Please wait 10 seconds between each post."}`, ErrRateLimited},
+		{http.StatusOK, `{"status":1,"message":"Paste is limited to 10 MiB of encrypted data."}`, ErrTooLarge},
+		{http.StatusOK, `{"status":1,"message":"Wrong deletion token. Paste was not deleted."}`, ErrWrongToken},
+		{http.StatusOK, `{"status":1,"message":"Discussion is disabled for this paste."}`, ErrNoDiscussion},
+		{http.StatusOK, `{"status":1,"message":"Invalid data."}`, ErrRejected},
+		{http.StatusInternalServerError, `{"status":1,"message":"Error saving paste. Sorry."}`, ErrRejected},
+	} {
+		_, err := decodeReply("https://h.example.org/", tc.code, []byte(tc.body))
+		se := (*ServerError)(nil)
+		if !errors.Is(err, tc.want) || !errors.As(err, &se) || se.StatusCode != tc.code {
+			t.Errorf("%d %s: got %v, want %v", tc.code, tc.body, err, tc.want)
+		}
+	}
+	m, err := decodeReply("https://h.example.org/", http.StatusOK, []byte(`{"status":0,"id":"abc"}`))
+	if err != nil || m["id"] != "abc" {
+		t.Errorf("got %v, %v", m, err)
+	}
+	_, err = decodeReply("https://h.example.org/", http.StatusOK, []byte(`{"status":1,"message":"Invalid data."}`))
+	if err.Error() != "https://h.example.org/: Invalid data." {
+		t.Errorf("message %q", err)
+	}
+}
+
+func TestErrorsKeepSecretsOut(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// nothing listens there once closed
+	addr := ln.Addr().String()
+	ln.Close()
+	c := NewClient(nil)
+	_, err = c.getJSON(context.Background(), "http://"+addr+"/?pasteid=0011223344556677&deletetoken=s3cr3t")
+	if !errors.Is(err, ErrHostDown) {
+		t.Fatalf("got %v, want ErrHostDown", err)
+	}
+	if strings.Contains(err.Error(), "s3cr3t") || strings.Contains(err.Error(), "pasteid") {
+		t.Errorf("the request url is in %q", err)
+	}
+	if !errors.Is(ErrWrongKey, ErrDecrypt) || !errors.Is(ErrWrongPassword, ErrDecrypt) || decryptFailed("pass") != ErrWrongPassword {
+		t.Error("decrypt errors do not match ErrDecrypt")
+	}
+}
@@ -0,0 +1,107 @@
+package pbin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestServerErrorFailover(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{statusError("h", http.StatusInternalServerError), true},
+		{statusError("h", http.StatusBadGateway), true},
+		{statusError("h", http.StatusNotFound), false},
+		{statusError("h", http.StatusRequestEntityTooLarge), false},
+		{statusError("h", http.StatusTooManyRequests), false},
+		// a privatebin refusal, another host may have other limits
+		{messageError("h", http.StatusOK, "Paste is limited to 10 MiB of encrypted data."), true},
+		{messageError("h", http.StatusOK, "Please wait 10 seconds between each post."), true},
+		{&ServerError{Host: "h", Kind: ErrHostDown, Cause: errors.New("connection refused")}, true},
+		{statusError("h", http.StatusForbidden), false},
+		{&ServerError{Host: "h", StatusCode: http.StatusOK, Kind: ErrHostDown, Cause: errNotJSON}, true},
+		{fmt.Errorf("wrapped: %w", statusError("h", http.StatusServiceUnavailable)), true},
+		{ErrHostDown, false},
+		{errors.New("other"), false},
+	} {
+		if got := isFailover(tc.err); got != tc.want {
+			t.Errorf("%v: failover %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+// failingHost answers every post with code and counts them
+func failingHost(t *testing.T, code int, posts *int32) *testHost {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(posts, 1)
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(ts.Close)
+	return &testHost{Server: ts}
+}
+
+func TestSendFailsOver(t *testing.T) {
+	pings, posts := int32(0), int32(0)
+	c := copiesClient(t, &pings)
+	good := newTestHost(t)
+	addTestHost(t, c, good, false)
+	addTestHost(t, c, failingHost(t, http.StatusBadGateway, &posts), false)
+	addTestHost(t, c, failingHost(t, http.StatusServiceUnavailable, &posts), false)
+	p, _ := c.CraftPaste([]byte("somewhere"))
+	r, err := p.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Host != good.URL+"/" || good.pastes(t) != 1 {
+		t.Fatalf("sent to %s", r.Host)
+	}
+	// the down hosts tried first, if any, are in the attempts
+	if len(r.Attempts) != int(posts)+1 || r.Attempts[len(r.Attempts)-1].Err != nil {
+		t.Errorf("attempts %+v after %d failed posts", r.Attempts, posts)
+	}
+	for _, a := range r.Attempts[:len(r.Attempts)-1] {
+		if !errors.Is(a.Err, ErrHostDown) {
+			t.Errorf("attempt %s failed with %v", a.Host, a.Err)
+		}
+	}
+}
+
+func TestSendMaxAttempts(t *testing.T) {
+	pings, posts := int32(0), int32(0)
+	c := copiesClient(t, &pings)
+	for i := 0; i < 4; i++ {
+		addTestHost(t, c, failingHost(t, http.StatusInternalServerError, &posts), false)
+	}
+	p, _ := c.CraftPaste([]byte("nowhere"))
+	p.SetMaxAttempts(2)
+	_, err := p.Send()
+	if !errors.Is(err, ErrHostDown) {
+		t.Fatalf("got %v, want ErrHostDown", err)
+	}
+	if posts != 2 || len(p.Attempts()) != 2 {
+		t.Errorf("%d posts, %d attempts, want 2", posts, len(p.Attempts()))
+	}
+	// the client default applies when the paste has none
+	posts = 0
+	c.MaxAttempts = 3
+	p, _ = c.CraftPaste([]byte("nowhere"))
+	p.Send()
+	if posts != 3 {
+		t.Errorf("%d posts with the client default of 3", posts)
+	}
+	// an error another host would give as well stops right away
+	posts = 0
+	c = copiesClient(t, &pings)
+	for i := 0; i < 3; i++ {
+		addTestHost(t, c, failingHost(t, http.StatusNotFound, &posts), false)
+	}
+	p, _ = c.CraftPaste([]byte("nowhere"))
+	if _, err = p.Send(); !errors.Is(err, ErrNotFound) || posts != 1 {
+		t.Errorf("got %v after %d posts", err, posts)
+	}
+}
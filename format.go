@@ -0,0 +1,56 @@
+package pbin
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	// iso dates, syslog dates and bracketed timestamps at the start of a line
+	logLineRegexp = regexp.MustCompile(`^(\[?\d{4}[-/]\d{2}[-/]\d{2}|[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}|\[\d+[.:]\d+)`)
+)
+
+// DetectFormat picks a display format from the file name and the content:
+// markdown for .md files, plaintext for logs and syntax highlighting otherwise.
+// name may be empty when the content comes from a pipe
+func DetectFormat(name string, b []byte) string {
+	base := strings.ToLower(filepath.Base(name))
+	switch filepath.Ext(base) {
+	case ".md", ".markdown":
+		{
+			return FormatMarkdown
+		}
+	case ".log", ".txt":
+		{
+			return FormatPlainText
+		}
+	}
+	// rotated logs like app.log.1
+	if strings.Contains(base, ".log.") {
+		return FormatPlainText
+	}
+	if looksLikeLog(b) {
+		return FormatPlainText
+	}
+	return defaultFormat
+}
+
+// looksLikeLog is true when most of the first lines start with a timestamp
+func looksLikeLog(b []byte) bool {
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	lines, stamped := 0, 0
+	for sc.Scan() && lines < 10 {
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines++
+		if logLineRegexp.MatchString(line) {
+			stamped++
+		}
+	}
+	return lines > 0 && stamped*2 >= lines
+}
@@ -0,0 +1,45 @@
+package pbin
+
+import "testing"
+
+func TestDetectFormat(t *testing.T) {
+	code := []byte("package main\n\nfunc main() {}\n")
+	logs := []byte("2024-01-02 10:00:00 start\n2024-01-02 10:00:01 ready\n\n2024-01-02 10:00:02 stop\n")
+	syslog := []byte("Jan  2 10:00:00 host sshd[1]: accepted\nJan  2 10:00:01 host sshd[1]: closed\n")
+	for _, tc := range []struct {
+		name string
+		b    []byte
+		want string
+	}{
+		{"README.md", code, FormatMarkdown},
+		{"/docs/Guide.MARKDOWN", code, FormatMarkdown},
+		{"notes.txt", code, FormatPlainText},
+		{"app.log", code, FormatPlainText},
+		{"app.log.1", code, FormatPlainText},
+		{"main.go", code, FormatSyntaxHighlighting},
+		{"", code, FormatSyntaxHighlighting},
+		{"", logs, FormatPlainText},
+		{"", syslog, FormatPlainText},
+		{"", []byte("[12.345] kernel: up\n[12.400] kernel: disk\n"), FormatPlainText},
+		{"", []byte{}, FormatSyntaxHighlighting},
+		// a name wins over the content
+		{"CHANGES.md", logs, FormatMarkdown},
+	} {
+		if got := DetectFormat(tc.name, tc.b); got != tc.want {
+			t.Errorf("%q %q: got %s, want %s", tc.name, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestSetFormat(t *testing.T) {
+	p, _ := NewClient(nil).CraftPaste([]byte("x"))
+	if p.Format() != defaultFormat {
+		t.Errorf("default format %s", p.Format())
+	}
+	if p.SetFormat(FormatMarkdown) != nil || p.Format() != FormatMarkdown {
+		t.Errorf("format %s", p.Format())
+	}
+	if p.SetFormat("rainbow") == nil || p.Format() != FormatMarkdown {
+		t.Errorf("unknown format taken, now %s", p.Format())
+	}
+}
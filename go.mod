@@ -5,4 +5,6 @@ go 1.16
 require (
 	github.com/gearnode/base58 v0.0.0-20200201175139-69e2d70f0e30
 	golang.org/x/crypto v0.0.0-20210506145944-38f3c27a63bf
+	golang.org/x/net v0.0.0-20210226172049-e18ecbb05110
+	golang.org/x/term v0.0.0-20201126162022-7de9c90e9dd1
 )
@@ -2,17 +2,17 @@ package pbin
 
 import (
 	// "log"
+	"context"
+	"errors"
 	mrand "math/rand"
 	"net"
 	"net/url"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
-var (
-	hosts = processHosts()
-)
-
 const (
 	unknown    option  = iota // unknown
 	Hour       Expiry  = iota // expires after 1 hour
@@ -29,9 +29,12 @@ const (
 
 type (
 	host struct {
-		api      *url.URL
-		expiry   []Expiry
-		features []Feature
+		api       *url.URL
+		expiry    []Expiry
+		features  []Feature
+		shortener string // link is appended, defaults to the yourls proxy of the host
+		builtin   bool   // from the public directory
+		version   int    // api version, 1 for zerobin and privatebin before 1.3, 0 is 2
 	}
 	db struct {
 		hosts []*host
@@ -330,7 +333,7 @@ func processHosts() *db {
 		if err != nil || u == nil {
 			panic(err)
 		}
-		d.addHost(&host{u, h.ex, h.op})
+		d.addHost(&host{api: u, expiry: h.ex, features: h.op, builtin: true})
 	}
 	return d
 }
@@ -372,6 +375,102 @@ func (d *db) addHost(h *host) {
 	}
 }
 
+// removeHosts drops every host matching fn and rebuilds the feature index
+func (d *db) removeHosts(fn func(h *host) bool) {
+	d.Lock()
+	defer d.Unlock()
+	kept := []*host{}
+	for _, h := range d.hosts {
+		if !fn(h) {
+			kept = append(kept, h)
+		}
+	}
+	d.hosts = kept
+	for o, hsts := range d.feats {
+		kept := []*host{}
+		for _, h := range hsts {
+			if !fn(h) {
+				kept = append(kept, h)
+			}
+		}
+		d.feats[o] = kept
+	}
+}
+
+// DisablePublicHosts removes the hosts of the public directory compiled into pbin,
+// only hosts added with AddHost or from a config file are used afterwards
+func DisablePublicHosts() {
+	DefaultClient.DisablePublicHosts()
+}
+
+func (c *Client) DisablePublicHosts() {
+	c.hosts.removeHosts(func(h *host) bool {
+		return h.builtin
+	})
+}
+
+func (d *db) getHost(api string) *host {
+	d.RLock()
+	defer d.RUnlock()
+	for _, h := range d.hosts {
+		if h.api.String() == api {
+			return h
+		}
+	}
+	return nil
+}
+
+// AddHost registers a privatebin instance with the expiry options and features it allows,
+// it is then used by Send and SetHost like the built in hosts. A host already
+// registered under the same url is replaced
+func AddHost(api string, ex []Expiry, feats []Feature) error {
+	return DefaultClient.AddHost(api, ex, feats)
+}
+
+func (c *Client) AddHost(api string, ex []Expiry, feats []Feature) error {
+	u, err := parseHostURL(api)
+	if err != nil {
+		return err
+	}
+	c.hosts.removeHosts(func(h *host) bool {
+		return h.api.String() == u.String()
+	})
+	c.hosts.addHost(&host{api: u, expiry: ex, features: feats})
+	return nil
+}
+
+func parseHostURL(api string) (*url.URL, error) {
+	u, err := url.Parse(api)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, errors.New("invalid host url: " + api)
+	}
+	// plain http is only for onion services, which tor already encrypts, and local instances
+	if u.Scheme != "https" && !(u.Scheme == "http" && (isOnion(u) || isLoopback(u))) {
+		return nil, errors.New("host url must use https, or http for .onion and local hosts: " + api)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u, nil
+}
+
+func isOnion(u *url.URL) bool {
+	return strings.HasSuffix(strings.ToLower(u.Hostname()), ".onion")
+}
+
+func isLoopback(u *url.URL) bool {
+	if u.Hostname() == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(u.Hostname())
+	return ip != nil && ip.IsLoopback()
+}
+
 // func (d *db) getAllHosts() []*host {
 // 	d.RLock()
 // 	defer d.RUnlock()
@@ -406,8 +505,32 @@ func (d *db) filterHosts(ex Expiry, feats []Feature) []*host {
 	return mixHosts(hsts)
 }
 
-func (h *host) ping() bool {
-	c, err := net.DialTimeout("tcp", net.JoinHostPort(h.api.Hostname(), "443"), 5*time.Second)
+func (h *host) hasExpiry(ex Expiry) bool {
+	for _, e := range h.expiry {
+		if e == ex {
+			return true
+		}
+	}
+	return false
+}
+
+// supports explains which of the requested options the host does not allow
+func (h *host) supports(ex Expiry, feats []Feature) error {
+	if !h.hasExpiry(ex) {
+		return errors.New("host " + h.api.String() + " does not allow expiry " + ex.String())
+	}
+	for _, f := range feats {
+		if !h.hasFeature(f) {
+			return errors.New("host " + h.api.String() + " does not allow " + f.String())
+		}
+	}
+	return nil
+}
+
+func (h *host) ping(ctx context.Context, dial dialFunc) bool {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	c, err := dial(ctx, "tcp", net.JoinHostPort(h.api.Hostname(), h.port()))
 	if err != nil {
 		return false
 	}
@@ -445,7 +568,121 @@ func (e Expiry) String() string {
 	return ""
 }
 
-func findFastest(hsts []*host) *host {
+func (e Expiry) MarshalText() ([]byte, error) {
+	return []byte(e.String()), nil
+}
+
+func (e *Expiry) UnmarshalText(b []byte) error {
+	ex, err := ParseExpiry(string(b))
+	if err != nil {
+		return err
+	}
+	*e = ex
+	return nil
+}
+
+func (f Feature) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+func (f *Feature) UnmarshalText(b []byte) error {
+	ft, err := ParseFeature(string(b))
+	if err != nil {
+		return err
+	}
+	*f = ft
+	return nil
+}
+
+// ParseExpiry accepts the values of Expiry.String and the cli flags like "week" or "-week"
+func ParseExpiry(es string) (Expiry, error) {
+	switch {
+	case strings.Contains(es, "hour"):
+		{
+			return Hour, nil
+		}
+	case strings.Contains(es, "day"):
+		{
+			return Day, nil
+		}
+	case strings.Contains(es, "week"):
+		{
+			return Week, nil
+		}
+	case strings.Contains(es, "month"):
+		{
+			return Month, nil
+		}
+	case strings.Contains(es, "year"):
+		{
+			return Year, nil
+		}
+	case strings.Contains(es, "never"):
+		{
+			return Never, nil
+		}
+	}
+	return 0, errors.New("unknown expiry: " + es)
+}
+
+// ParseFeature accepts the values of Feature.String, spaces are optional
+func ParseFeature(fs string) (Feature, error) {
+	switch strings.ReplaceAll(strings.ToLower(fs), " ", "") {
+	case "burn", "burnafterreading":
+		{
+			return Burn, nil
+		}
+	case "discussion":
+		{
+			return Discussion, nil
+		}
+	case "uploadfile", "fileupload":
+		{
+			return UploadFile, nil
+		}
+	case "shortenurl", "urlshortener":
+		{
+			return ShortenURL, nil
+		}
+	}
+	return 0, errors.New("unknown feature: " + fs)
+}
+
+func (f Feature) String() string {
+	switch f {
+	case Burn:
+		{
+			return "burn"
+		}
+	case Discussion:
+		{
+			return "discussion"
+		}
+	case UploadFile:
+		{
+			return "upload file"
+		}
+	case ShortenURL:
+		{
+			return "shorten url"
+		}
+	}
+	return ""
+}
+
+func (h *host) port() string {
+	if p := h.api.Port(); p != "" {
+		return p
+	}
+	if h.api.Scheme == "http" {
+		return "80"
+	}
+	return "443"
+}
+
+// rankHosts pings up to 25 of hsts at once and returns the reachable ones, fastest first,
+// canceling ctx aborts the pings still running
+func rankHosts(ctx context.Context, dial dialFunc, hsts []*host) []*host {
 	num := 25
 	if len(hsts) < num {
 		num = len(hsts)
@@ -454,33 +691,33 @@ func findFastest(hsts []*host) *host {
 		h       *host
 		elapsed time.Duration
 	}
-	fastestChan := make(chan *host)
 	resultsChan := make(chan result, num)
-	go func(in <-chan result, out chan<- *host) {
-		best := (*result)(nil)
-		for r := range in {
-			// gets smallest value
-			if best == nil || r.elapsed < best.elapsed {
-				best = &r
-			}
-		}
-		out <- best.h
-		close(out)
-	}(resultsChan, fastestChan)
 	wg := sync.WaitGroup{}
 	for _, hs := range hsts[:num] {
 		wg.Add(1)
 		go func(h *host, out chan<- result) {
 			defer wg.Done()
 			start := time.Now()
-			if h.ping() {
-				out <- result{h, time.Until(start)}
+			if h.ping(ctx, dial) {
+				out <- result{h, time.Since(start)}
 			}
 		}(hs, resultsChan)
 	}
 	wg.Wait()
 	close(resultsChan)
-	return <-fastestChan
+	results := []result{}
+	for r := range resultsChan {
+		results = append(results, r)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		// smallest value first
+		return results[i].elapsed < results[j].elapsed
+	})
+	ranked := []*host{}
+	for _, r := range results {
+		ranked = append(ranked, r.h)
+	}
+	return ranked
 }
 
 func mixHosts(hsts []*host) []*host {
@@ -488,7 +725,7 @@ func mixHosts(hsts []*host) []*host {
 	mrand.Seed(time.Now().UnixNano())
 	mix := mrand.Perm(len(hsts))
 	for _, v := range mix {
-		rhts = append(hsts, hsts[v])
+		rhts = append(rhts, hsts[v])
 	}
 	return rhts
 }
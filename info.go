@@ -0,0 +1,164 @@
+package pbin
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+)
+
+type (
+	// PasteInfo is what the host and the clear part of a paste tell about it
+	PasteInfo struct {
+		PasteID          string     `json:"id"`
+		Host             string     `json:"host"`
+		Version          int        `json:"version"`           // api version, 1 or 2
+		Created          *time.Time `json:"created,omitempty"` // nil when the host does not tell
+		Expires          *time.Time `json:"expires,omitempty"` // nil when the paste does not expire
+		TTL              int64      `json:"ttl,omitempty"`     // seconds left when Info ran, 0 when the paste does not expire
+		Format           string     `json:"format"`
+		BurnAfterReading bool       `json:"burnafterreading"`
+		OpenDiscussion   bool       `json:"opendiscussion"`
+		// a v2 attachment is inside the ciphertext, it is not known when a password is needed
+		Attachment    bool `json:"attachment"`
+		Comments      int  `json:"comments"`
+		NeedsPassword bool `json:"needspassword"` // or the url key is wrong
+		CipherSize    int  `json:"ciphersize"`    // bytes of paste and attachment ciphertext
+	}
+)
+
+// TimeToLive is the time left until the paste expires, zero when it does not
+func (pi *PasteInfo) TimeToLive() time.Duration {
+	if pi.Expires == nil {
+		return 0
+	}
+	return time.Until(*pi.Expires)
+}
+
+// Info downloads the paste at ur and reports on it without returning its content,
+// the paste is decrypted to learn whether a password is needed.
+// Like any read it destroys a burn after reading paste
+func Info(ur *url.URL) (*PasteInfo, error) {
+	return DefaultClient.InfoContext(context.Background(), ur)
+}
+
+// InfoContext is Info with a context for the request and the body read
+func InfoContext(ctx context.Context, ur *url.URL) (*PasteInfo, error) {
+	return DefaultClient.InfoContext(ctx, ur)
+}
+
+func (c *Client) Info(ur *url.URL) (*PasteInfo, error) {
+	return c.InfoContext(context.Background(), ur)
+}
+
+func (c *Client) InfoContext(ctx context.Context, ur *url.URL) (*PasteInfo, error) {
+	hostAPI, pID, key, err := splitPasteURL(ur)
+	if err != nil {
+		return nil, err
+	}
+	err = checkKey(key)
+	if err != nil {
+		return nil, err
+	}
+	m, err := c.fetchPaste(ctx, hostAPI, pID)
+	if err != nil {
+		return nil, err
+	}
+	pi := &PasteInfo{
+		PasteID: pID,
+		Host:    hostAPI.String(),
+	}
+	meta, _ := m["meta"].(map[string]interface{})
+	pi.readTimes(meta)
+	if cs, ok := m["comments"].([]interface{}); ok {
+		pi.Comments = len(cs)
+	} else if n, ok := m["comment_count"].(float64); ok {
+		pi.Comments = int(n)
+	}
+	p := &Paste{}
+	if isLegacyPaste(m) {
+		pi.Version = 1
+		p.displayFormat = FormatPlainText
+		p.readLegacyMeta(meta)
+		pi.Attachment = m["attachment"] != nil
+		pi.CipherSize = sjclSize(m["data"]) + sjclSize(m["attachment"]) + sjclSize(m["attachmentname"])
+		_, err = openSJCL(key, "", m["data"])
+	} else {
+		pi.Version = PrivateBinAPIVersion
+		adatav, ok := m["adata"].([]interface{})
+		if !ok || len(adatav) == 0 {
+			return nil, ErrInvalidPaste
+		}
+		p.readAData(adatav)
+		cts, _ := m["ct"].(string)
+		ct := []byte(nil)
+		ct, err = decodeBase64(cts)
+		if err != nil {
+			return nil, ErrInvalidPaste
+		}
+		pi.CipherSize = len(ct)
+		secret := []byte(nil)
+		secret, err = decodeKey(key)
+		if err != nil {
+			return nil, err
+		}
+		pd := map[string]interface{}(nil)
+		pd, err = openJSON(secret, "", m["ct"], adatav[0], adatav)
+		if err == nil {
+			pi.Attachment = pd["attachment"] != nil
+		}
+	}
+	if errors.Is(err, ErrDecrypt) {
+		pi.NeedsPassword = true
+	} else if err != nil {
+		return nil, err
+	}
+	pi.Format = p.displayFormat
+	pi.BurnAfterReading = p.burnAfterReading
+	pi.OpenDiscussion = p.openDiscussion
+	return pi, nil
+}
+
+// readTimes takes the names of every version: privatebin 1.3 and later send
+// created and time_to_live, 1.x postdate and remaining_time, zerobin expire_date
+func (pi *PasteInfo) readTimes(meta map[string]interface{}) {
+	for _, k := range []string{"created", "postdate"} {
+		if ts, ok := meta[k].(float64); ok {
+			t := time.Unix(int64(ts), 0)
+			pi.Created = &t
+			break
+		}
+	}
+	for _, k := range []string{"time_to_live", "remaining_time"} {
+		if ttl, ok := meta[k].(float64); ok {
+			t := time.Now().Add(time.Duration(ttl) * time.Second).Truncate(time.Second)
+			pi.setExpires(t)
+			return
+		}
+	}
+	if ts, ok := meta["expire_date"].(float64); ok {
+		pi.setExpires(time.Unix(int64(ts), 0))
+	}
+}
+
+func (pi *PasteInfo) setExpires(t time.Time) {
+	pi.Expires = &t
+	pi.TTL = int64(time.Until(t).Round(time.Second).Seconds())
+	// an expiring paste keeps a ttl in the json even in its last second
+	if pi.TTL < 1 {
+		pi.TTL = 1
+	}
+}
+
+// sjclSize is the ciphertext size of sjcl json, 0 when v is missing or malformed
+func sjclSize(v interface{}) int {
+	sd, err := parseSJCL(v)
+	if err != nil {
+		return 0
+	}
+	ct, err := decodeBase64(sd.CT)
+	if err != nil {
+		return 0
+	}
+	return len(ct)
+}
@@ -0,0 +1,74 @@
+package pbin
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadTimes(t *testing.T) {
+	for _, tc := range []struct {
+		meta    string
+		created bool
+		ttl     int64
+	}{
+		{`{"created":1600000000,"time_to_live":3600}`, true, 3600},
+		{`{"postdate":1600000000,"remaining_time":60}`, true, 60},
+		{`{"expire_date":` + strconv.FormatInt(time.Now().Unix()+300, 10) + `}`, false, 300},
+		{`{"time_to_live":0}`, false, 1},
+		{`{}`, false, 0},
+	} {
+		meta := map[string]interface{}{}
+		json.Unmarshal([]byte(tc.meta), &meta)
+		pi := &PasteInfo{}
+		pi.readTimes(meta)
+		if (pi.Created != nil) != tc.created || pi.TTL < tc.ttl-1 || pi.TTL > tc.ttl {
+			t.Errorf("%s: created %v, ttl %d", tc.meta, pi.Created, pi.TTL)
+		}
+		if (pi.Expires == nil) != (tc.ttl == 0) {
+			t.Errorf("%s: expires %v", tc.meta, pi.Expires)
+		}
+	}
+}
+
+func TestInfoJSON(t *testing.T) {
+	b, _ := json.Marshal(&PasteInfo{PasteID: "0011223344556677"})
+	// unknown times are left out rather than sent as the zero time
+	for _, k := range []string{"created", "expires", "ttl"} {
+		if strings.Contains(string(b), `"`+k+`"`) {
+			t.Errorf("%s in %s", k, b)
+		}
+	}
+	if (&PasteInfo{}).TimeToLive() != 0 {
+		t.Error("a paste that does not expire has a time to live")
+	}
+}
+
+func TestInfo(t *testing.T) {
+	pings := int32(0)
+	c := copiesClient(t, &pings)
+	h := newTestHost(t)
+	addTestHost(t, c, h, false)
+	p, _ := c.CraftPaste([]byte("# secret"))
+	p.SetFormat("markdown")
+	p.SetExpiry("hour")
+	p.OpenDiscussion(true)
+	p.SetPassword("pw")
+	r, err := p.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := r.PasteURL()
+	pi, err := c.Info(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pi.Version != 2 || pi.Format != "markdown" || !pi.OpenDiscussion || !pi.NeedsPassword || pi.Created == nil || pi.TTL < 3500 || pi.TTL > 3600 || pi.CipherSize == 0 {
+		t.Errorf("info %+v", pi)
+	}
+	if ttl := pi.TimeToLive(); ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("time to live %s", ttl)
+	}
+}
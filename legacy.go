@@ -0,0 +1,432 @@
+package pbin
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gearnode/base58"
+)
+
+// v1 pastes, as written by zerobin and privatebin before 1.3, are sjcl json
+// of the base64 of the raw deflated text, encrypted with the base64 url key
+// itself as the pbkdf2 password
+
+const (
+	legacyKDFIterations int = 10000 // the sjcl default
+	legacyIVSize        int = 16    // bytes
+)
+
+type (
+	// sjclData is the json sjcl.encrypt writes, privatebin checks the exact key set
+	sjclData struct {
+		IV     string `json:"iv"`
+		V      int    `json:"v"`
+		Iter   int    `json:"iter"`
+		KS     int    `json:"ks"`
+		TS     int    `json:"ts"`
+		Mode   string `json:"mode"`
+		AData  string `json:"adata"`
+		Cipher string `json:"cipher"`
+		Salt   string `json:"salt"`
+		CT     string `json:"ct"`
+	}
+)
+
+// isLegacyPaste tells a v1 reply, with the sjcl json in data, from a v2 one
+func isLegacyPaste(m map[string]interface{}) bool {
+	_, ct := m["ct"]
+	_, data := m["data"]
+	return data && !ct
+}
+
+// checkKey rejects a url key that neither a v1 nor a v2 paste can have,
+// before the paste is downloaded and possibly burnt
+func checkKey(key string) error {
+	if _, err := base58.Decode(key); err == nil {
+		return nil
+	}
+	if _, err := base64.StdEncoding.DecodeString(legacyKey(key)); err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: paste key is neither base58 nor base64", ErrInvalidURL)
+}
+
+// legacyKey undoes what links go through: text appended after an & and a stripped =
+func legacyKey(key string) string {
+	if i := strings.Index(key, "&"); i > -1 {
+		key = key[:i]
+	}
+	if !strings.HasSuffix(key, "=") {
+		key += "="
+	}
+	return key
+}
+
+// legacyPassword is the sjcl password, privatebin appends the sha256 of the user password
+func legacyPassword(key string, pass string) string {
+	if strings.TrimSpace(pass) == "" {
+		return key
+	}
+	h := sha256.Sum256([]byte(pass))
+	return key + hex.EncodeToString(h[:])
+}
+
+// sealSJCL compresses and encrypts b like privatebin 1.x, the result is the sjcl json
+func sealSJCL(key string, pass string, b []byte) (string, error) {
+	buf := bytes.Buffer{}
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	_, err = w.Write(b)
+	if err != nil {
+		return "", err
+	}
+	err = w.Close()
+	if err != nil {
+		return "", err
+	}
+	cs := &CipherSpec{
+		IV:          randomBytes(legacyIVSize),
+		Salt:        randomBytes(SaltSize),
+		Iterations:  legacyKDFIterations,
+		KeySize:     256,
+		TagSize:     TagSize,
+		Algorithm:   EncryptionAlgorithm,
+		Mode:        EncryptionMode,
+		Compression: "none",
+	}
+	aead, err := cs.aead(cs.key([]byte(legacyPassword(key, pass)), ""))
+	if err != nil {
+		return "", err
+	}
+	compressed := base64.StdEncoding.EncodeToString(buf.Bytes())
+	sd := &sjclData{
+		IV:     base64.StdEncoding.EncodeToString(cs.IV),
+		V:      1,
+		Iter:   cs.Iterations,
+		KS:     cs.KeySize,
+		TS:     cs.TagSize,
+		Mode:   cs.Mode,
+		Cipher: cs.Algorithm,
+		Salt:   base64.StdEncoding.EncodeToString(cs.Salt),
+		CT:     base64.StdEncoding.EncodeToString(aead.Seal(nil, cs.IV, []byte(compressed), nil)),
+	}
+	sjson, err := json.Marshal(sd)
+	if err != nil {
+		return "", err
+	}
+	return string(sjson), nil
+}
+
+// parseSJCL reads the sjcl json either as a string or already decoded
+func parseSJCL(v interface{}) (*sjclData, error) {
+	sd := &sjclData{}
+	switch d := v.(type) {
+	case string:
+		{
+			err := json.Unmarshal([]byte(d), sd)
+			if err != nil {
+				return nil, ErrInvalidPaste
+			}
+		}
+	case map[string]interface{}:
+		{
+			b, err := json.Marshal(d)
+			if err != nil {
+				return nil, ErrInvalidPaste
+			}
+			err = json.Unmarshal(b, sd)
+			if err != nil {
+				return nil, ErrInvalidPaste
+			}
+		}
+	default:
+		{
+			return nil, ErrInvalidPaste
+		}
+	}
+	return sd, nil
+}
+
+// openSJCL decrypts and inflates the sjcl json v
+func openSJCL(key string, pass string, v interface{}) ([]byte, error) {
+	sd, err := parseSJCL(v)
+	if err != nil {
+		return nil, err
+	}
+	cs := &CipherSpec{
+		Iterations:  sd.Iter,
+		KeySize:     sd.KS,
+		TagSize:     sd.TS,
+		Algorithm:   sd.Cipher,
+		Mode:        sd.Mode,
+		Compression: "none",
+	}
+	iv, err1 := decodeBase64(sd.IV)
+	salt, err2 := decodeBase64(sd.Salt)
+	ct, err3 := decodeBase64(sd.CT)
+	adata, err4 := decodeBase64(sd.AData)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		return nil, ErrInvalidPaste
+	}
+	cs.IV = iv
+	cs.Salt = salt
+	err = cs.validate()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cs.aead(cs.key([]byte(legacyPassword(legacyKey(key), pass)), ""))
+	if err != nil {
+		return nil, err
+	}
+	compressed, err := aead.Open(nil, cs.IV, ct, adata)
+	if err != nil {
+		return nil, decryptFailed(pass)
+	}
+	flated, err := decodeBase64(string(compressed))
+	if err != nil {
+		return nil, ErrInvalidPaste
+	}
+	return inflate(flated)
+}
+
+// legacyForm is the v1 upload, the url key is the base64 of the same secret a v2 host gets
+func (p *Paste) legacyForm() (url.Values, error) {
+	key := base64.StdEncoding.EncodeToString(p.urlSecret[:])
+	data, err := sealSJCL(key, p.userPassword, p.clearTextData)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	form.Set("data", data)
+	form.Set("expire", p.expiry.String())
+	form.Set("formatter", p.displayFormat)
+	form.Set("burnafterreading", "0")
+	if p.burnAfterReading {
+		form.Set("burnafterreading", "1")
+	}
+	form.Set("opendiscussion", "0")
+	if p.openDiscussion {
+		form.Set("opendiscussion", "1")
+	}
+	if p.attachment != nil {
+		attachment, err := sealSJCL(key, p.userPassword, []byte(makeDataURI(p.attachmentName, p.attachment)))
+		if err != nil {
+			return nil, err
+		}
+		name, err := sealSJCL(key, p.userPassword, []byte(p.attachmentName))
+		if err != nil {
+			return nil, err
+		}
+		form.Set("attachment", attachment)
+		form.Set("attachmentname", name)
+	}
+	return form, nil
+}
+
+func (c *Comment) legacyForm() (url.Values, error) {
+	data, err := sealSJCL(legacyKey(c.key), c.userPassword, c.clearTextData)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	form.Set("data", data)
+	form.Set("pasteid", c.pasteid)
+	form.Set("parentid", c.parentid)
+	if c.nickname != "" {
+		nickname, err := sealSJCL(legacyKey(c.key), c.userPassword, []byte(c.nickname))
+		if err != nil {
+			return nil, err
+		}
+		form.Set("nickname", nickname)
+	}
+	return form, nil
+}
+
+func (c *Client) openLegacyPaste(hostAPI *url.URL, key string, pass string, m map[string]interface{}) (*Paste, error) {
+	b, err := openSJCL(key, pass, m["data"])
+	if err != nil {
+		return nil, err
+	}
+	p := &Paste{
+		client:        c,
+		hostAPI:       hostAPI,
+		clearTextData: b,
+		userPassword:  pass,
+		displayFormat: FormatPlainText,
+		version:       1,
+	}
+	if secret, err := base64.StdEncoding.DecodeString(legacyKey(key)); err == nil && len(secret) == KDFSecretSize {
+		copy(p.urlSecret[:], secret)
+	}
+	if meta, ok := m["meta"].(map[string]interface{}); ok {
+		p.readLegacyMeta(meta)
+	}
+	if m["attachment"] != nil {
+		uri, err := openSJCL(key, pass, m["attachment"])
+		if err != nil {
+			return nil, err
+		}
+		p.attachment, err = parseDataURI(string(uri))
+		if err != nil {
+			return nil, err
+		}
+		if m["attachmentname"] != nil {
+			name, err := openSJCL(key, pass, m["attachmentname"])
+			if err != nil {
+				return nil, err
+			}
+			p.attachmentName = string(name)
+		}
+	}
+	pID, _ := m["id"].(string)
+	p.comments = []*Comment{}
+	if cs, ok := m["comments"].([]interface{}); ok {
+		for _, cv := range cs {
+			cm, ok := cv.(map[string]interface{})
+			if !ok {
+				return nil, ErrInvalidPaste
+			}
+			cmt, err := openLegacyComment(hostAPI, key, pass, cm)
+			if err != nil {
+				return nil, err
+			}
+			if cmt.pasteid == "" {
+				cmt.pasteid = pID
+			}
+			cmt.client = c
+			p.comments = append(p.comments, cmt)
+		}
+	}
+	return p, nil
+}
+
+// readLegacyMeta reads the options privatebin 1.x and zerobin keep in clear
+func (p *Paste) readLegacyMeta(meta map[string]interface{}) {
+	if v, ok := meta["syntaxcoloring"].(bool); ok && v {
+		p.displayFormat = FormatSyntaxHighlighting
+	}
+	if v, ok := meta["formatter"].(string); ok && v != "" {
+		p.displayFormat = v
+	}
+	p.openDiscussion, _ = meta["opendiscussion"].(bool)
+	p.burnAfterReading, _ = meta["burnafterreading"].(bool)
+}
+
+func openLegacyComment(hostAPI *url.URL, key string, pass string, cm map[string]interface{}) (*Comment, error) {
+	b, err := openSJCL(key, pass, cm["data"])
+	if err != nil {
+		return nil, err
+	}
+	c := &Comment{
+		hostAPI:       hostAPI,
+		clearTextData: b,
+		userPassword:  pass,
+		version:       1,
+		key:           key,
+	}
+	c.id, _ = cm["id"].(string)
+	c.pasteid, _ = cm["pasteid"].(string)
+	c.parentid, _ = cm["parentid"].(string)
+	if meta, ok := cm["meta"].(map[string]interface{}); ok {
+		if meta["nickname"] != nil {
+			nickname, err := openSJCL(key, pass, meta["nickname"])
+			if err != nil {
+				return nil, err
+			}
+			c.nickname = string(nickname)
+		}
+		if ts, ok := meta["postdate"].(float64); ok {
+			c.postdate = time.Unix(int64(ts), 0)
+		}
+	}
+	return c, nil
+}
+
+// fetchPaste reads the paste data of any api version, zerobin answers with
+// a html page and only knows the bare ?id query
+func (c *Client) fetchPaste(ctx context.Context, hostAPI *url.URL, pID string) (map[string]interface{}, error) {
+	api := hostAPI.String()
+	code, resBody, err := c.get(ctx, api+"?pasteid="+pID)
+	if err != nil {
+		return nil, err
+	}
+	m, err := decodeReply(api, code, resBody)
+	if !errors.Is(err, errNotJSON) {
+		return m, err
+	}
+	code, resBody, err = c.get(ctx, api+"?"+pID)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := parseZeroBinPage(pID, resBody); ok {
+		return m, nil
+	}
+	if msg := htmlDiv(resBody, "errormessage"); msg != "" {
+		return nil, messageError(api, code, msg)
+	}
+	return decodeReply(api, code, resBody)
+}
+
+func (c *Client) get(ctx context.Context, u string) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	return c.doRequest(req, strings.Split(u, "?")[0])
+}
+
+// parseZeroBinPage turns the messages zerobin puts in its page, the paste then
+// its comments, into the reply of the privatebin 1.x json api
+func parseZeroBinPage(pID string, b []byte) (map[string]interface{}, bool) {
+	data := htmlDiv(b, "cipherdata")
+	if data == "" {
+		return nil, false
+	}
+	msgs := []map[string]interface{}{}
+	err := json.Unmarshal([]byte(data), &msgs)
+	if err != nil || len(msgs) == 0 {
+		return nil, false
+	}
+	m := msgs[0]
+	m["id"] = pID
+	comments := []interface{}{}
+	for _, cm := range msgs[1:] {
+		if meta, ok := cm["meta"].(map[string]interface{}); ok {
+			cm["id"] = meta["commentid"]
+			cm["parentid"] = meta["parentid"]
+		}
+		comments = append(comments, cm)
+	}
+	m["comments"] = comments
+	return m, true
+}
+
+// htmlDiv returns the unescaped text of the div with id
+func htmlDiv(b []byte, id string) string {
+	s := string(b)
+	i := strings.Index(s, `id="`+id+`"`)
+	if i < 0 {
+		return ""
+	}
+	s = s[i:]
+	i = strings.Index(s, ">")
+	j := strings.Index(s, "</div>")
+	if i < 0 || j < i {
+		return ""
+	}
+	return strings.TrimSpace(html.UnescapeString(s[i+1 : j]))
+}
@@ -0,0 +1,121 @@
+package pbin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// The fixtures were encrypted outside of this package, with node's crypto module
+// and the parameters and shapes of the original hosts:
+// zerobin.html is a zerobin 0.19 page, aes-128-ccm with a 64 bit tag and 1000 iterations,
+// privatebin1.json a privatebin 1.x reply, aes-256-gcm with 100000 iterations and a password
+const (
+	zeroBinID   = "a3e5e3c4b2c8f1d0"
+	zeroBinKey  = "uZdRMPF79B+tY4GEvO2leaZdPJ5qfzWp0K3t1LrqYgw="
+	legacyID    = "7d1a9e3c5b2f4a60"
+	legacyKey1x = "5eE+CjyJI3rtE52ufXF580upy3D3xga+GxFokr7L1Ic="
+	legacyPass  = "hunter2"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	b, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestOpenZeroBinPage(t *testing.T) {
+	page := readFixture(t, "zerobin.html")
+	// zerobin only knows ?ID and answers anything else with its empty page
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.RawQuery == zeroBinID {
+			w.Write(page)
+			return
+		}
+		w.Write([]byte(`<html><div id="cipherdata" style="display:none;"></div></html>`))
+	}))
+	defer ts.Close()
+	c := NewClient(ts.Client())
+	u, _ := url.Parse(ts.URL + "/?" + zeroBinID + "#" + zeroBinKey)
+	p, err := c.OpenPasteContext(context.Background(), u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(p.Text()) != "hello from zerobin" {
+		t.Errorf("text %q", p.Text())
+	}
+	if p.Version() != 1 || !p.openDiscussion {
+		t.Errorf("version %d, discussion %v", p.Version(), p.openDiscussion)
+	}
+	if len(p.Comments()) != 1 {
+		t.Fatalf("%d comments", len(p.Comments()))
+	}
+	cm := p.Comments()[0]
+	if string(cm.Text()) != "a zerobin comment" || cm.Nickname() != "bob" || cm.ID() != "5a1b2c3d4e5f6a7b" || cm.ParentID() != zeroBinID {
+		t.Errorf("comment %q by %q, id %s parent %s", cm.Text(), cm.Nickname(), cm.ID(), cm.ParentID())
+	}
+}
+
+func TestParseZeroBinPage(t *testing.T) {
+	m, ok := parseZeroBinPage(zeroBinID, readFixture(t, "zerobin.html"))
+	if !ok {
+		t.Fatal("cipherdata not found")
+	}
+	b, err := openSJCL(zeroBinKey, "", m["data"])
+	if err != nil || string(b) != "hello from zerobin" {
+		t.Fatalf("got %q, %v", b, err)
+	}
+	if _, ok = parseZeroBinPage(zeroBinID, []byte(`<html><div id="status"></div></html>`)); ok {
+		t.Error("a page without cipherdata was parsed")
+	}
+}
+
+func TestOpenPrivateBin1x(t *testing.T) {
+	m := map[string]interface{}{}
+	err := json.Unmarshal(readFixture(t, "privatebin1.json"), &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostAPI, _ := url.Parse("https://paste.example.org/")
+	c := NewClient(nil)
+	_, err = c.openPaste(hostAPI, legacyKey1x, "", m)
+	if !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("without the password got %v", err)
+	}
+	p, err := c.openPaste(hostAPI, legacyKey1x, legacyPass, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(p.Text()) != "hello from privatebin 1.x" || p.Format() != FormatMarkdown {
+		t.Errorf("text %q, format %s", p.Text(), p.Format())
+	}
+	name, att := p.Attachment()
+	if name != "notes.txt" || string(att) != "attached" {
+		t.Errorf("attachment %q %q", name, att)
+	}
+}
+
+func TestSealSJCLRoundTrip(t *testing.T) {
+	for _, pass := range []string{"", legacyPass} {
+		s, err := sealSJCL(legacyKey1x, pass, []byte("round trip ü"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(s, `"mode":"gcm"`) {
+			t.Errorf("sealed %s", s)
+		}
+		b, err := openSJCL(legacyKey1x, pass, s)
+		if err != nil || string(b) != "round trip ü" {
+			t.Errorf("pass %q: got %q, %v", pass, b, err)
+		}
+	}
+}
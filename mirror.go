@@ -0,0 +1,77 @@
+package pbin
+
+import (
+	"errors"
+	"time"
+)
+
+// Expiry is the expiry class, of a received paste the shortest class that covers
+// the time it has left, Never when it does not expire
+func (p *Paste) Expiry() Expiry {
+	return p.expiry
+}
+
+// Mirror crafts a copy of a received paste to send to another host, with its text,
+// attachment, format, expiry class, discussion, burn after reading and password.
+// The copy gets a fresh key unless keepKey, then a host of the same api version
+// gives a link with the same fragment. Send picks another host than the one of
+// the original unless SetHost asks for it. Comments are not copied
+func (p *Paste) Mirror(keepKey bool) (*Paste, error) {
+	if p.hostAPI == nil {
+		return nil, errors.New("only a received paste can be mirrored")
+	}
+	if keepKey && p.urlSecret == ([KDFSecretSize]byte{}) {
+		return nil, errors.New("the key of this paste can not be reused")
+	}
+	m := p.clone(keepKey)
+	m.skipHosts = []string{p.hostAPI.String()}
+	return m, nil
+}
+
+// clone crafts a paste with the content and settings of p, with a fresh key unless keepKey
+func (p *Paste) clone(keepKey bool) *Paste {
+	m := &Paste{client: p.client}
+	m.init(p.clearTextData)
+	if keepKey {
+		m.urlSecret = p.urlSecret
+	}
+	if p.displayFormat != "" {
+		m.displayFormat = p.displayFormat
+	}
+	m.expiry = p.expiry
+	m.openDiscussion = p.openDiscussion
+	m.burnAfterReading = p.burnAfterReading
+	m.userPassword = p.userPassword
+	m.attachment = p.attachment
+	m.attachmentName = p.attachmentName
+	return m
+}
+
+// readExpiry sets the expiry class from the meta of a received paste
+func (p *Paste) readExpiry(meta map[string]interface{}) {
+	pi := &PasteInfo{}
+	pi.readTimes(meta)
+	p.expiry = expiryClass(pi.TimeToLive())
+}
+
+// expiryClass is the shortest expiry that lasts ttl, 0 is Never
+func expiryClass(ttl time.Duration) Expiry {
+	if ttl == 0 {
+		return Never
+	}
+	for _, c := range []struct {
+		ex Expiry
+		d  time.Duration
+	}{
+		{Hour, time.Hour},
+		{Day, 24 * time.Hour},
+		{Week, 7 * 24 * time.Hour},
+		{Month, 31 * 24 * time.Hour},
+		{Year, 366 * 24 * time.Hour},
+	} {
+		if ttl <= c.d {
+			return c.ex
+		}
+	}
+	return Never
+}
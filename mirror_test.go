@@ -0,0 +1,80 @@
+package pbin_test
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cbluth/pbin"
+	"github.com/cbluth/pbin/pbintest"
+)
+
+func TestMirror(t *testing.T) {
+	a, b := pbintest.NewServer(), pbintest.NewServer()
+	defer a.Close()
+	defer b.Close()
+	c := a.Client()
+	err := c.AddHost(b.API(), pbintest.Expiry, pbintest.Features)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := c.CraftPaste([]byte("copy me"))
+	p.SetFormat(pbin.FormatMarkdown)
+	p.SetExpiry("day")
+	p.SetPassword("hunter2")
+	p.OpenDiscussion(true)
+	p.SetAttachment("notes.txt", []byte("attached"))
+	if _, err = p.Mirror(false); err == nil {
+		t.Fatal("mirrored a paste that was never received")
+	}
+	p.SetHost(a.API())
+	r, err := p.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := r.PasteURL()
+	open := func(u *url.URL) *pbin.Paste {
+		got, err := c.OpenPasteWithPromptContext(context.Background(), u, "hunter2", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	got := open(u)
+	m, err := got.Mirror(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the other host is picked without SetHost
+	mr, err := m.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu, _ := mr.PasteURL()
+	if mr.Host != b.API() || mu.Fragment == u.Fragment {
+		t.Fatalf("mirror on %s with fragment %s", mr.Host, mu.Fragment)
+	}
+	cp := open(mu)
+	name, att := cp.Attachment()
+	if string(cp.Text()) != "copy me" || cp.Format() != pbin.FormatMarkdown || cp.Expiry() != pbin.Day || name != "notes.txt" || string(att) != "attached" {
+		t.Errorf("copy %q as %s, expiry %s, attachment %q %q", cp.Text(), cp.Format(), cp.Expiry(), name, att)
+	}
+	cm, _ := c.CraftComment(mu, []byte("still open"))
+	cm.SetPassword("hunter2")
+	if _, err = cm.Send(); err != nil {
+		t.Errorf("discussion of the copy: %v", err)
+	}
+	// with the same key the link only differs in the host
+	m, err = got.Mirror(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kr, err := m.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(kr.URL, "#"+u.Fragment) || kr.Host != b.API() {
+		t.Errorf("same key mirror at %s", kr.URL)
+	}
+}
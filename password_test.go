@@ -0,0 +1,64 @@
+package pbin
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPassword(t *testing.T) {
+	pings := int32(0)
+	c := copiesClient(t, &pings)
+	h := newTestHost(t)
+	addTestHost(t, c, h, false)
+	p, _ := c.CraftPaste([]byte("behind a password"))
+	p.SetPassword("right")
+	r, err := p.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := r.PasteURL()
+	if _, err = c.OpenPaste(u); !errors.Is(err, ErrWrongKey) || !errors.Is(err, ErrDecrypt) {
+		t.Errorf("without a password got %v", err)
+	}
+	ctx := context.Background()
+	if _, err = c.OpenPasteWithPromptContext(ctx, u, "wrong", nil); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("with a wrong password got %v", err)
+	}
+	giveUp := errors.New("no terminal")
+	_, err = c.OpenPasteWithPromptContext(ctx, u, "", func() (string, error) { return "", giveUp })
+	if !errors.Is(err, giveUp) {
+		t.Errorf("a failing prompt got %v", err)
+	}
+	got, err := c.OpenPasteWithPromptContext(ctx, u, "right", nil)
+	if err != nil || string(got.Text()) != "behind a password" {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestPromptKeepsBurnPaste(t *testing.T) {
+	pings := int32(0)
+	c := copiesClient(t, &pings)
+	h := newTestHost(t)
+	addTestHost(t, c, h, false)
+	p, _ := c.CraftPaste([]byte("read once"))
+	p.SetPassword("right")
+	p.BurnAfterRead(true)
+	r, err := p.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := r.PasteURL()
+	// the paste is downloaded once, the retry with the prompted password does not read it again
+	prompts := 0
+	got, err := c.OpenPasteWithPromptContext(context.Background(), u, "", func() (string, error) {
+		prompts++
+		return "right", nil
+	})
+	if err != nil || string(got.Text()) != "read once" || prompts != 1 {
+		t.Fatalf("got %v after %d prompts", err, prompts)
+	}
+	if _, err = c.OpenPaste(u); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second read got %v", err)
+	}
+}
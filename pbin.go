@@ -3,6 +3,7 @@ package pbin
 import (
 	"bytes"
 	"compress/flate"
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
@@ -13,7 +14,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"strconv"
+	"path/filepath"
 	"strings"
 
 	"github.com/gearnode/base58"
@@ -42,18 +43,23 @@ const (
 	// Never   Expiry = "never"
 
 	//
-	defaultFormat            string = formatSyntaxHighlighting
-	formatSyntaxHighlighting string = "syntaxhighlighting"
+	defaultFormat            string = FormatSyntaxHighlighting
+	FormatPlainText          string = "plaintext"
+	FormatSyntaxHighlighting string = "syntaxhighlighting"
+	FormatMarkdown           string = "markdown"
 	defaultExpiry            Expiry = Week
 	defaultOpenDiscussion    bool   = false
 	defaultBurnAfterReading  bool   = false
+	defaultMaxAttempts       int    = 3
 )
 
 type (
 	Paste struct {
 		//
 		pasteid          [PasteIDSize]byte // in hex
+		client           *Client
 		hostAPI          *url.URL
+		host             *host
 		clearTextData    []byte
 		cipherJSONData   []byte
 		urlSecret        [KDFSecretSize]byte
@@ -65,15 +71,27 @@ type (
 		openDiscussion   bool
 		burnAfterReading bool
 		userPassword     string
-		shortURL 		 string
+		shorten          bool
+		shortURL         string
+		attachment       []byte
+		attachmentName   string
+		comments         []*Comment
+		maxAttempts      int
+		attempts         []Attempt
+		version          int      // api version of the host, 1 or 2
+		skipHosts        []string // apis Send leaves out when it picks the host
+		copies           int
+	}
+	// Attempt records a host Send tried, Err is nil for the host that took the paste
+	Attempt struct {
+		Host string
+		Err  error
 	}
 	// Expiry string
 )
 
 func CraftPaste(b []byte) (*Paste, error) {
-	p := &Paste{}
-	p.init(b)
-	return p, nil
+	return DefaultClient.CraftPaste(b)
 }
 
 func (p *Paste) init(b []byte) *Paste {
@@ -90,32 +108,33 @@ func (p *Paste) init(b []byte) *Paste {
 }
 
 func (p *Paste) SetExpiry(es string) {
-	switch {
-	case strings.Contains(es, "hour"):
-		{
-			p.expiry = Hour
-		}
-	case strings.Contains(es, "day"):
-		{
-			p.expiry = Day
-		}
-	case strings.Contains(es, "week"):
-		{
-			p.expiry = Week
-		}
-	case strings.Contains(es, "month"):
-		{
-			p.expiry = Month
-		}
-	case strings.Contains(es, "year"):
-		{
-			p.expiry = Year
-		}
-	case strings.Contains(es, "never"):
+	if ex, err := ParseExpiry(es); err == nil {
+		p.expiry = ex
+	}
+}
+
+// SetFormat chooses how the web ui displays the paste,
+// one of FormatPlainText, FormatSyntaxHighlighting or FormatMarkdown
+func (p *Paste) SetFormat(f string) error {
+	switch f {
+	case FormatPlainText, FormatSyntaxHighlighting, FormatMarkdown:
 		{
-			p.expiry = Never
+			p.displayFormat = f
+			return nil
 		}
 	}
+	return errors.New("unknown format: " + f)
+}
+
+// Format is the display format, of a received paste as well
+func (p *Paste) Format() string {
+	return p.displayFormat
+}
+
+// Version is the api version of the host of a sent or received paste,
+// 1 for zerobin and privatebin before 1.3, 0 before Send
+func (p *Paste) Version() int {
+	return p.version
 }
 
 func (p *Paste) SetPassword(pass string) {
@@ -130,14 +149,124 @@ func (p *Paste) OpenDiscussion(openDiscussion bool) {
 	p.openDiscussion = openDiscussion
 }
 
-func (p *Paste) Send() (*url.URL, map[string]interface{}, error) {
-	err := p.encrypt()
+// SetAttachment adds a file to the paste, it is only sent to hosts with UploadFile
+func (p *Paste) SetAttachment(name string, b []byte) {
+	p.attachmentName = filepath.Base(name)
+	p.attachment = b
+}
+
+// SetHost makes Send post to api directly, without asking the directory or pinging hosts,
+// Send fails when the host does not allow the expiry or features of the paste.
+// Hosts not known from the registry or AddHost are expected to run the privatebin defaults
+func (p *Paste) SetHost(api string) error {
+	u, err := parseHostURL(api)
 	if err != nil {
-		return nil, nil, err
+		return err
+	}
+	p.host = p.getClient().hosts.getHost(u.String())
+	if p.host == nil {
+		p.host = &host{
+			api:      u,
+			expiry:   []Expiry{Hour, Day, Week, Month, Year, Never},
+			features: []Feature{Burn, Discussion},
+		}
+	}
+	return nil
+}
+
+// Shorten restricts Send to hosts with ShortenURL and asks the host for a short link
+func (p *Paste) Shorten(shorten bool) {
+	p.shorten = shorten
+}
+
+// ShortURL is set by Send when Shorten was requested
+func (p *Paste) ShortURL() string {
+	return p.shortURL
+}
+
+// SetMaxAttempts caps how many hosts Send tries when a host is down or refuses the paste,
+// 1 turns the failover off
+func (p *Paste) SetMaxAttempts(n int) {
+	p.maxAttempts = n
+}
+
+// Attempts lists the hosts the last Send tried, in order
+func (p *Paste) Attempts() []Attempt {
+	return p.attempts
+}
+
+func (p *Paste) getClient() *Client {
+	if p.client == nil {
+		return DefaultClient
+	}
+	return p.client
+}
+
+// Send uploads the paste, when only the shortening or some copies fail the result is still returned with the error
+func (p *Paste) Send() (*SendResult, error) {
+	return p.SendContext(context.Background())
+}
+
+// SendContext is Send with a context, it bounds the host pings, the upload and the shortening
+func (p *Paste) SendContext(ctx context.Context) (*SendResult, error) {
+	err := error(nil)
+	c := p.getClient()
+	if int(p.expiry) == 0 {
+		p.expiry = c.Expiry
 	}
 	if int(p.expiry) == 0 {
 		p.expiry = defaultExpiry
 	}
+	// the hosts are pinged once, the copies walk the same ranking
+	candidates, others := []*host{}, []*host(nil)
+	if p.host != nil {
+		err = p.host.supports(p.expiry, p.getFeatures())
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, p.host)
+		if p.copies > 1 {
+			others, err = p.registeredHosts(ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		candidates = rankHosts(ctx, c.dialer(), p.usableHosts(nil))
+		if len(candidates) == 0 {
+			return nil, ErrNoHost
+		}
+		others = candidates
+	}
+	r, err := p.sendTo(ctx, candidates)
+	if err != nil {
+		return r, err
+	}
+	if p.copies > 1 {
+		return r, p.sendCopies(ctx, r, others)
+	}
+	return r, nil
+}
+
+// usableHosts are the registered hosts that take the expiry and features of p
+// and are not skipped, narrowed by keep when it is not nil
+func (p *Paste) usableHosts(keep func(h *host) bool) []*host {
+	hsts := []*host{}
+	for _, h := range p.getClient().hosts.filterHosts(p.expiry, p.getFeatures()) {
+		if !p.skips(h.api.String()) && (keep == nil || keep(h)) {
+			hsts = append(hsts, h)
+		}
+	}
+	return hsts
+}
+
+// sendTo uploads the paste to the first of candidates that takes it, then shortens the link
+func (p *Paste) sendTo(ctx context.Context, candidates []*host) (*SendResult, error) {
+	err := p.encrypt()
+	if err != nil {
+		return nil, err
+	}
+	c := p.getClient()
 	reqb := map[string]interface{}{}
 	reqb["v"] = PrivateBinAPIVersion
 	reqb["adata"] = p.makeAData()
@@ -146,46 +275,84 @@ func (p *Paste) Send() (*url.URL, map[string]interface{}, error) {
 	reqb["ct"] = base64.RawStdEncoding.EncodeToString(p.cipherJSONData)
 	requestBodyJSONData, err := json.Marshal(&reqb)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	hsts := hosts.filterHosts(p.expiry, p.getFeatures())
-	host := findFastest(hsts)
-	req, err := http.NewRequest(http.MethodPost, host.api.String(), bytes.NewBuffer(requestBodyJSONData))
-	if err != nil {
-		return nil, nil, err
+	maxAttempts := p.maxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = c.MaxAttempts
 	}
-	req.Header.Set("X-Requested-With", "JSONHttpRequest")
-	client := &http.Client{}
-	res, err := client.Do(req)
-	if err != nil {
-		return nil, nil, err
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
 	}
-	defer res.Body.Close()
-	resBody, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return nil, nil, err
+	p.attempts = []Attempt{}
+	host := (*host)(nil)
+	resm := map[string]interface{}(nil)
+	legacyForm := url.Values(nil)
+	for _, h := range candidates {
+		if len(p.attempts) >= maxAttempts {
+			break
+		}
+		// the same ciphertext goes to every host, the url secret does not change
+		if h.version == 1 {
+			if legacyForm == nil {
+				legacyForm, err = p.legacyForm()
+				if err != nil {
+					return nil, err
+				}
+			}
+			resm, err = c.postForm(ctx, h.api, legacyForm)
+		} else {
+			resm, err = c.postJSON(ctx, h.api, requestBodyJSONData)
+		}
+		p.attempts = append(p.attempts, Attempt{Host: h.api.String(), Err: err})
+		if err == nil {
+			host = h
+			break
+		}
+		if !isFailover(err) || ctx.Err() != nil {
+			return nil, err
+		}
 	}
-	if res.StatusCode != http.StatusOK {
-		return nil, nil, errors.New(
-			"error " + strconv.Itoa(res.StatusCode) +
-				"\nfrom server: " + host.api.String() +
-				"\nrequest body:" + string(requestBodyJSONData) +
-				"\nresponse body:" + string(resBody),
-		)
+	if host == nil {
+		return nil, err
 	}
-	resm := map[string]interface{}{}
-	err = json.Unmarshal(resBody, &resm)
-	if err != nil {
-		return nil, nil, err
+	p.hostAPI = host.api
+	p.version = PrivateBinAPIVersion
+	if host.version == 1 {
+		p.version = 1
 	}
-	if resm["status"].(float64) != 0 {
-		return nil, nil, errors.New("error from server: " + resm["message"].(string))
+	id, ok := resm["id"].(string)
+	if !ok {
+		return nil, &ServerError{Host: host.api.String(), StatusCode: http.StatusOK, Kind: ErrRejected, Cause: errors.New("missing paste id in response")}
 	}
-	purl, err := url.Parse(host.api.String() + "?" + resm["id"].(string) + "#" + base58.Encode(p.urlSecret[:]))
+	key := base58.Encode(p.urlSecret[:])
+	if host.version == 1 {
+		key = base64.StdEncoding.EncodeToString(p.urlSecret[:])
+	}
+	purl, err := url.Parse(host.api.String() + "?" + id + "#" + key)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	return purl, resm, nil
+	r := &SendResult{
+		PasteID:  id,
+		URL:      purl.String(),
+		Host:     host.api.String(),
+		Expiry:   p.expiry,
+		Features: p.getFeatures(),
+		Attempts: p.attempts,
+	}
+	r.DeleteToken, _ = resm["deletetoken"].(string)
+	if r.DeleteToken != "" {
+		r.DeleteURL = makeDeleteURL(host.api.String(), id, r.DeleteToken)
+	}
+	if p.shorten {
+		p.shortURL, err = c.shorten(ctx, host, purl.String())
+		r.ShortURL = p.shortURL
+		if err != nil {
+			return r, err
+		}
+	}
+	return r, nil
 }
 
 func randomBytes(n int) []byte {
@@ -198,46 +365,113 @@ func randomBytes(n int) []byte {
 }
 
 func (p *Paste) encrypt() error {
-	clearJSONData, err := json.Marshal(
-		&map[string]interface{}{
-			"paste": string(p.clearTextData),
-		},
-	)
+	clear := map[string]interface{}{
+		"paste": string(p.clearTextData),
+	}
+	if p.attachment != nil {
+		clear["attachment"] = makeDataURI(p.attachmentName, p.attachment)
+		clear["attachment_name"] = p.attachmentName
+	}
+	clearJSONData, err := json.Marshal(&clear)
 	if err != nil {
 		return err
 	}
-	if p.userPassword != "" {
-		copy(p.aESKey[:], makeAESKey(append(p.urlSecret[:], []byte(p.userPassword)...), p.salt[:]))
-	} else {
-		copy(p.aESKey[:], makeAESKey(p.urlSecret[:], p.salt[:]))
-	}
-	c, err := aes.NewCipher(p.aESKey[:])
+	copy(p.aESKey[:], deriveKey(p.urlSecret[:], p.userPassword, p.salt[:]))
+	p.cipherJSONData, err = sealJSON(p.aESKey[:], p.nonce[:], clearJSONData, p.makeAData())
 	if err != nil {
 		return err
 	}
+	return nil
+}
+
+func sealJSON(key []byte, nonce []byte, clearJSONData []byte, adatav interface{}) ([]byte, error) {
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
 	gcm, err := cipher.NewGCM(c)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	adata, err := json.Marshal(p.makeAData())
+	adata, err := json.Marshal(adatav)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	b := bytes.Buffer{}
 	w, err := flate.NewWriter(&b, flate.BestCompression)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	_, err = w.Write(clearJSONData)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	err = w.Close()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	p.cipherJSONData = gcm.Seal(nil, p.nonce[:], b.Bytes(), adata)
-	return nil
+	return gcm.Seal(nil, nonce, b.Bytes(), adata), nil
+}
+
+func (c *Client) postJSON(ctx context.Context, api *url.URL, requestBodyJSONData []byte) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, api.String(), bytes.NewBuffer(requestBodyJSONData))
+	if err != nil {
+		return nil, err
+	}
+	return c.doJSON(req, api.String())
+}
+
+// postForm is postJSON for v1 hosts, they only read form values
+func (c *Client) postForm(ctx context.Context, api *url.URL, form url.Values) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, api.String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.doJSON(req, api.String())
+}
+
+// doJSON runs a privatebin api request, errors never carry the request or response body
+func (c *Client) doJSON(req *http.Request, host string) (map[string]interface{}, error) {
+	code, resBody, err := c.doRequest(req, host)
+	if err != nil {
+		return nil, err
+	}
+	return decodeReply(host, code, resBody)
+}
+
+func (c *Client) doRequest(req *http.Request, host string) (int, []byte, error) {
+	req.Header.Set("X-Requested-With", "JSONHttpRequest")
+	res, err := c.do(req)
+	if err != nil {
+		return 0, nil, &ServerError{Host: host, Kind: ErrHostDown, Cause: stripURL(err)}
+	}
+	defer res.Body.Close()
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, &ServerError{Host: host, StatusCode: res.StatusCode, Kind: ErrHostDown, Cause: err}
+	}
+	return res.StatusCode, resBody, nil
+}
+
+func decodeReply(host string, code int, resBody []byte) (map[string]interface{}, error) {
+	resm := map[string]interface{}{}
+	err := json.Unmarshal(resBody, &resm)
+	if err != nil {
+		if code != http.StatusOK {
+			return nil, statusError(host, code)
+		}
+		// a 200 that is not json, like a captive portal, a broken instance or zerobin
+		return nil, &ServerError{Host: host, StatusCode: code, Kind: ErrHostDown, Cause: errNotJSON}
+	}
+	if status, ok := resm["status"].(float64); ok && status != 0 {
+		msg, _ := resm["message"].(string)
+		return nil, messageError(host, code, msg)
+	}
+	if code != http.StatusOK {
+		return nil, statusError(host, code)
+	}
+	return resm, nil
 }
 
 func (p *Paste) getFeatures() []Feature {
@@ -245,18 +479,24 @@ func (p *Paste) getFeatures() []Feature {
 	// discussion
 	// upload file
 	// shortenurl
+	feats := []Feature{}
 	switch {
-		case p.openDiscussion && p.burnAfterReading: {
-			return []Feature{}
-		}
-		case p.openDiscussion && !p.burnAfterReading: {
-			return []Feature{Discussion}
+	case p.openDiscussion && !p.burnAfterReading:
+		{
+			feats = append(feats, Discussion)
 		}
-		case !p.openDiscussion && p.burnAfterReading: {
-			return []Feature{Burn}
+	case !p.openDiscussion && p.burnAfterReading:
+		{
+			feats = append(feats, Burn)
 		}
 	}
-	return []Feature{}
+	if p.attachment != nil {
+		feats = append(feats, UploadFile)
+	}
+	if p.shorten {
+		feats = append(feats, ShortenURL)
+	}
+	return feats
 }
 
 func (p *Paste) makeAData() []interface{} {
@@ -269,22 +509,33 @@ func (p *Paste) makeAData() []interface{} {
 		burnAfterRead = 1
 	}
 	return []interface{}{
-		[]interface{}{
-			base64.RawStdEncoding.EncodeToString(p.nonce[:]), // IV
-			base64.RawStdEncoding.EncodeToString(p.salt[:]),  // salt
-			KDFIterations,
-			256,
-			TagSize,
-			EncryptionAlgorithm,
-			EncryptionMode,
-			DataCompression,
-		},
+		makeCipherSpec(p.nonce[:], p.salt[:]),
 		p.displayFormat,
 		openDiscussion,
 		burnAfterRead,
 	}
 }
 
+func makeCipherSpec(nonce []byte, salt []byte) []interface{} {
+	return []interface{}{
+		base64.RawStdEncoding.EncodeToString(nonce), // IV
+		base64.RawStdEncoding.EncodeToString(salt),  // salt
+		KDFIterations,
+		256,
+		TagSize,
+		EncryptionAlgorithm,
+		EncryptionMode,
+		DataCompression,
+	}
+}
+
+func deriveKey(secret []byte, pass string, salt []byte) []byte {
+	if pass != "" {
+		return makeAESKey(append(append([]byte{}, secret...), []byte(pass)...), salt)
+	}
+	return makeAESKey(secret, salt)
+}
+
 func makeAESKey(secret []byte, salt []byte) []byte {
 	return pbkdf2.Key(
 		secret,
@@ -296,89 +547,149 @@ func makeAESKey(secret []byte, salt []byte) []byte {
 }
 
 func GetPaste(ur *url.URL) ([]byte, error) {
-	pID := ur.RawQuery
-	b58Pass := ur.Fragment
-	hostURL := strings.Split(ur.String(), "?")[0]
-	pasteDataURL := hostURL + "?pasteid=" + pID
-	req, err := http.NewRequest(http.MethodGet, pasteDataURL, nil)
+	return GetPasteContext(context.Background(), ur)
+}
+
+// GetPasteContext is GetPaste with a context for the request and the body read
+func GetPasteContext(ctx context.Context, ur *url.URL) ([]byte, error) {
+	return DefaultClient.GetPasteContext(ctx, ur)
+}
+
+// GetPasteWithComments also decrypts the discussion of the paste,
+// comments are returned in the order the server lists them
+func GetPasteWithComments(ur *url.URL) ([]byte, []*Comment, error) {
+	p, err := OpenPaste(ur)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	req.Header.Set("X-Requested-With", "JSONHttpRequest")
-	client := &http.Client{}
-	res, err := client.Do(req)
+	return p.clearTextData, p.comments, nil
+}
+
+// OpenPaste downloads and decrypts the paste at ur with its attachment and comments
+func OpenPaste(ur *url.URL) (*Paste, error) {
+	return OpenPasteContext(context.Background(), ur)
+}
+
+// OpenPasteContext is OpenPaste with a context for the request and the body read
+func OpenPasteContext(ctx context.Context, ur *url.URL) (*Paste, error) {
+	return DefaultClient.OpenPasteContext(ctx, ur)
+}
+
+// GetPasteWithPassword is GetPaste for pastes sent with SetPassword
+func GetPasteWithPassword(ur *url.URL, pass string) ([]byte, error) {
+	p, err := OpenPasteWithPassword(ur, pass)
 	if err != nil {
 		return nil, err
 	}
-	defer res.Body.Close()
-	b, err := ioutil.ReadAll(res.Body)
+	return p.clearTextData, nil
+}
+
+// OpenPasteWithPassword is OpenPaste for pastes sent with SetPassword,
+// an error matching ErrDecrypt is returned when the key or the password does not match
+func OpenPasteWithPassword(ur *url.URL, pass string) (*Paste, error) {
+	return OpenPasteWithPrompt(ur, pass, nil)
+}
+
+// OpenPasteWithPrompt calls prompt for a password when pass is empty and the
+// url key alone can not decrypt the paste, the paste is only downloaded once
+// so burn after reading pastes survive the retry
+func OpenPasteWithPrompt(ur *url.URL, pass string, prompt func() (string, error)) (*Paste, error) {
+	return OpenPasteWithPromptContext(context.Background(), ur, pass, prompt)
+}
+
+// OpenPasteWithPromptContext is OpenPasteWithPrompt with a context for the request and the body read
+func OpenPasteWithPromptContext(ctx context.Context, ur *url.URL, pass string, prompt func() (string, error)) (*Paste, error) {
+	return DefaultClient.OpenPasteWithPromptContext(ctx, ur, pass, prompt)
+}
+
+func (c *Client) openPaste(hostAPI *url.URL, key string, pass string, m map[string]interface{}) (*Paste, error) {
+	if isLegacyPaste(m) {
+		return c.openLegacyPaste(hostAPI, key, pass, m)
+	}
+	secret, err := decodeKey(key)
 	if err != nil {
 		return nil, err
 	}
-	m := map[string]interface{}{}
-	err = json.Unmarshal(b, &m)
+	adatav, ok := m["adata"].([]interface{})
+	if !ok || len(adatav) == 0 {
+		return nil, ErrInvalidPaste
+	}
+	pd, err := openJSON(secret, pass, m["ct"], adatav[0], adatav)
 	if err != nil {
 		return nil, err
 	}
-	p := &Paste{}
-	if v, ok := m["ct"]; !ok {
-		return nil, errors.New("missing ct")
-	} else {
-		p.cipherJSONData, err = base64.RawStdEncoding.DecodeString(v.(string))
-		if err != nil {
-			return nil, err
-		}
-	}
-	adatav := (interface{})(nil)
-	if v, ok := m["adata"]; !ok {
-		return nil, errors.New("missing adata")
-	} else {
-		nonceData, err := base64.RawStdEncoding.DecodeString(((v.([]interface{})[0]).([]interface{})[0]).(string)) // wtf
+	v, ok := pd["paste"].(string)
+	if !ok {
+		return nil, ErrInvalidPaste
+	}
+	p := &Paste{
+		client:        c,
+		hostAPI:       hostAPI,
+		clearTextData: []byte(v),
+		userPassword:  pass,
+		version:       PrivateBinAPIVersion,
+	}
+	copy(p.urlSecret[:], secret)
+	p.readAData(adatav)
+	if uri, ok := pd["attachment"].(string); ok {
+		p.attachment, err = parseDataURI(uri)
 		if err != nil {
 			return nil, err
 		}
-		copy(p.nonce[:], nonceData)
-		saltData, err := base64.RawStdEncoding.DecodeString(((v.([]interface{})[0]).([]interface{})[1]).(string)) // wtf
-		if err != nil {
-			return nil, err
+		p.attachmentName, _ = pd["attachment_name"].(string)
+	}
+	p.comments = []*Comment{}
+	if cs, ok := m["comments"].([]interface{}); ok {
+		for _, cv := range cs {
+			cm, ok := cv.(map[string]interface{})
+			if !ok {
+				return nil, ErrInvalidPaste
+			}
+			cmt, err := openComment(hostAPI, secret, pass, cm)
+			if err != nil {
+				return nil, err
+			}
+			cmt.client = c
+			p.comments = append(p.comments, cmt)
 		}
-		copy(p.salt[:], saltData)
-		adatav = v
 	}
-	secret, err := base58.Decode(b58Pass)
-	if err != nil {
-		return nil, err
-	}
-	aesKey := makeAESKey(secret, p.salt[:])
-	c, err := aes.NewCipher(aesKey)
-	if err != nil {
-		return nil, err
-	}
-	gcm, err := cipher.NewGCM(c)
-	if err != nil {
-		return nil, err
-	}
-	adata, err := json.Marshal(adatav)
-	if err != nil {
-		return nil, err
-	}
-	flated, err := gcm.Open(nil, p.nonce[:], p.cipherJSONData, adata)
-	if err != nil {
-		return nil, err
+	return p, nil
+}
+
+// readAData restores the paste options from [spec, format, discussion, burn]
+func (p *Paste) readAData(adatav []interface{}) {
+	if len(adatav) > 1 {
+		p.displayFormat, _ = adatav[1].(string)
+	}
+	if len(adatav) > 2 {
+		if v, ok := adatav[2].(float64); ok {
+			p.openDiscussion = v == 1
+		}
 	}
-	fr := flate.NewReader(bytes.NewBuffer(flated))
-	defer fr.Close()
-	unflated, err := ioutil.ReadAll(fr)
-	if err != nil {
-		return nil, err
+	if len(adatav) > 3 {
+		if v, ok := adatav[3].(float64); ok {
+			p.burnAfterReading = v == 1
+		}
 	}
-	pd := map[string]interface{}{}
-	err = json.Unmarshal(unflated, &pd)
+}
+
+func (p *Paste) Text() []byte {
+	return p.clearTextData
+}
+
+// Attachment returns the file name and content, b is nil when there is no attachment
+func (p *Paste) Attachment() (name string, b []byte) {
+	return p.attachmentName, p.attachment
+}
+
+func (p *Paste) Comments() []*Comment {
+	return p.comments
+}
+
+func (c *Client) getJSON(ctx context.Context, pasteDataURL string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pasteDataURL, nil)
 	if err != nil {
 		return nil, err
 	}
-	if v, ok := pd["paste"]; ok {
-		return []byte(v.(string)), nil
-	}
-	return nil, errors.New("missing paste data")
+	return c.doJSON(req, strings.Split(pasteDataURL, "?")[0])
 }
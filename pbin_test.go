@@ -0,0 +1,51 @@
+package pbin
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSetHost(t *testing.T) {
+	pings, posts := int32(0), int32(0)
+	c := copiesClient(t, &pings)
+	registered, other := newTestHost(t), newTestHost(t)
+	addTestHost(t, c, other, false)
+	u, _ := parseHostURL(registered.URL + "/")
+	c.hosts.addHost(&host{api: u, expiry: []Expiry{Day}, features: []Feature{}})
+	p, _ := c.CraftPaste([]byte("there"))
+	if p.SetHost("ftp://nope.example.org/") == nil {
+		t.Error("took an ftp host")
+	}
+	// the registered limits of the host apply
+	p.SetHost(registered.URL)
+	p.SetExpiry("week")
+	if _, err := p.Send(); err == nil || !strings.Contains(err.Error(), "expiry") {
+		t.Fatalf("got %v", err)
+	}
+	p.SetExpiry("day")
+	r, err := p.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Host != registered.URL+"/" || other.pastes(t) != 0 || pings != 0 {
+		t.Errorf("sent to %s after %d pings", r.Host, pings)
+	}
+	// an unknown host takes every expiry with burn and discussion
+	unknown := newTestHost(t)
+	p, _ = c.CraftPaste([]byte("there"))
+	p.SetHost(unknown.URL + "/")
+	p.SetExpiry("never")
+	p.BurnAfterRead(true)
+	if r, err = p.Send(); err != nil || r.Host != unknown.URL+"/" {
+		t.Fatalf("got %v", err)
+	}
+	// a chosen host that is down does not fail over
+	down := failingHost(t, http.StatusServiceUnavailable, &posts)
+	p, _ = c.CraftPaste([]byte("there"))
+	p.SetHost(down.URL + "/")
+	if _, err = p.Send(); !errors.Is(err, ErrHostDown) || posts != 1 || other.pastes(t) != 0 {
+		t.Errorf("got %v after %d posts", err, posts)
+	}
+}
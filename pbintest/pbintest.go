@@ -0,0 +1,104 @@
+// Package pbintest runs an in-memory privatebin v2 host for tests,
+// pastes never leave the process:
+//
+//	s := pbintest.NewServer()
+//	defer s.Close()
+//	pbin.DefaultClient = s.Client()
+//	p, _ := pbin.CraftPaste([]byte("hello"))
+//	r, err := p.Send()
+package pbintest
+
+import (
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/cbluth/pbin"
+	"github.com/cbluth/pbin/server"
+)
+
+var (
+	// Expiry lists every expiry the server takes
+	Expiry = []pbin.Expiry{pbin.Hour, pbin.Day, pbin.Week, pbin.Month, pbin.Year, pbin.Never}
+	// Features lists every feature the server has
+	Features = []pbin.Feature{pbin.Burn, pbin.Discussion, pbin.UploadFile}
+)
+
+type (
+	// Server is a privatebin host on a loopback httptest server
+	Server struct {
+		*httptest.Server
+		Host   *server.Server
+		mu     sync.Mutex
+		offset time.Duration
+	}
+)
+
+// NewServer starts a server, stop it with Close
+func NewServer() *Server {
+	s := &Server{
+		Host: server.New(server.NewMemory()),
+	}
+	s.Host.Now = s.now
+	s.Server = httptest.NewServer(s.Host)
+	return s
+}
+
+// API is the host url to give pbin, with the trailing slash
+func (s *Server) API() string {
+	return s.URL + "/"
+}
+
+// Client returns a pbin client that only knows this server,
+// assign it to pbin.DefaultClient for the package level functions
+func (s *Server) Client() *pbin.Client {
+	c := pbin.NewClient(s.Server.Client())
+	err := s.Register(c)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Register makes the server the only host of c
+func (s *Server) Register(c *pbin.Client) error {
+	err := c.ApplyConfig(&pbin.Config{Replace: true})
+	if err != nil {
+		return err
+	}
+	return c.AddHost(s.API(), Expiry, Features)
+}
+
+// Advance moves the server clock forward to let pastes expire
+func (s *Server) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offset += d
+}
+
+func (s *Server) now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Now().Add(s.offset)
+}
+
+// Has tells whether the paste id exists and has not expired
+func (s *Server) Has(id string) bool {
+	_, err := s.Host.Paste(id)
+	return err == nil
+}
+
+// Len counts the pastes that have not expired
+func (s *Server) Len() int {
+	ids, err := s.Host.Storage.List()
+	if err != nil {
+		return 0
+	}
+	n := 0
+	for _, id := range ids {
+		if s.Has(id) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,223 @@
+package pbintest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cbluth/pbin"
+	"github.com/cbluth/pbin/pbintest"
+)
+
+func setup(t *testing.T) (*pbintest.Server, *pbin.Client) {
+	s := pbintest.NewServer()
+	t.Cleanup(s.Close)
+	return s, s.Client()
+}
+
+func send(t *testing.T, p *pbin.Paste) (*pbin.SendResult, *url.URL) {
+	r, err := p.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := r.PasteURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, u
+}
+
+func TestSendAndRead(t *testing.T) {
+	s, c := setup(t)
+	p, _ := c.CraftPaste([]byte("hello pbintest"))
+	p.SetFormat(pbin.FormatMarkdown)
+	r, u := send(t, p)
+	if r.Host != s.API() || !s.Has(r.PasteID) || s.Len() != 1 {
+		t.Fatalf("paste %s not on %s", r.PasteID, s.API())
+	}
+	got, err := c.OpenPaste(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got.Text()) != "hello pbintest" || got.Format() != pbin.FormatMarkdown || got.Version() != 2 {
+		t.Errorf("got %q as %s, version %d", got.Text(), got.Format(), got.Version())
+	}
+	u.Fragment = "3vQB7B6MrGQZaxCuFg4oh" // a key of the wrong size
+	if _, err = c.OpenPaste(u); err == nil {
+		t.Error("opened with a wrong key")
+	}
+}
+
+func TestComments(t *testing.T) {
+	_, c := setup(t)
+	p, _ := c.CraftPaste([]byte("discuss this"))
+	p.OpenDiscussion(true)
+	r, u := send(t, p)
+	cm, err := c.CraftComment(u, []byte("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm.SetNickname("alice")
+	first, err := cm.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == "" || cm.ID() != first {
+		t.Fatalf("comment id %q, ID() %q", first, cm.ID())
+	}
+	reply, _ := c.CraftComment(u, []byte("second"))
+	reply.ReplyTo(first)
+	if _, err = reply.Send(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.OpenPaste(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := got.Comments()
+	if len(cs) != 2 {
+		t.Fatalf("%d comments", len(cs))
+	}
+	if string(cs[0].Text()) != "first" || cs[0].Nickname() != "alice" || cs[0].ParentID() != r.PasteID || cs[0].PostDate().IsZero() {
+		t.Errorf("first comment %q by %q under %s", cs[0].Text(), cs[0].Nickname(), cs[0].ParentID())
+	}
+	if string(cs[1].Text()) != "second" || cs[1].ParentID() != first {
+		t.Errorf("reply %q under %s", cs[1].Text(), cs[1].ParentID())
+	}
+	// a paste without discussion refuses comments
+	p, _ = c.CraftPaste([]byte("quiet"))
+	_, u = send(t, p)
+	cm, _ = c.CraftComment(u, []byte("hello?"))
+	if _, err = cm.Send(); !errors.Is(err, pbin.ErrNoDiscussion) {
+		t.Errorf("got %v, want ErrNoDiscussion", err)
+	}
+}
+
+func TestAttachment(t *testing.T) {
+	_, c := setup(t)
+	data := []byte{0, 1, 2, 0xff, 'g', 'i', 'f'}
+	p, _ := c.CraftPaste([]byte("see attached"))
+	p.SetAttachment("/some/dir/cat.gif", data)
+	_, u := send(t, p)
+	got, err := c.OpenPaste(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, b := got.Attachment()
+	if name != "cat.gif" || !bytes.Equal(b, data) || string(got.Text()) != "see attached" {
+		t.Errorf("attachment %q %x, text %q", name, b, got.Text())
+	}
+}
+
+func TestPassword(t *testing.T) {
+	_, c := setup(t)
+	p, _ := c.CraftPaste([]byte("secret"))
+	p.SetPassword("hunter2")
+	_, u := send(t, p)
+	if _, err := c.OpenPaste(u); !errors.Is(err, pbin.ErrDecrypt) {
+		t.Fatalf("without password got %v", err)
+	}
+	asked := 0
+	got, err := c.OpenPasteWithPromptContext(context.Background(), u, "", func() (string, error) {
+		asked++
+		return "hunter2", nil
+	})
+	if err != nil || asked != 1 || string(got.Text()) != "secret" {
+		t.Fatalf("got %v after %d prompts", err, asked)
+	}
+	if _, err = c.OpenPasteWithPromptContext(context.Background(), u, "wrong", nil); !errors.Is(err, pbin.ErrDecrypt) {
+		t.Errorf("wrong password got %v", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	s, c := setup(t)
+	p, _ := c.CraftPaste([]byte("oops"))
+	r, u := send(t, p)
+	if r.DeleteToken == "" || !strings.Contains(r.DeleteURL, r.DeleteToken) {
+		t.Fatalf("delete token %q, url %q", r.DeleteToken, r.DeleteURL)
+	}
+	if err := c.DeletePaste(u, "0000"); !errors.Is(err, pbin.ErrWrongToken) {
+		t.Fatalf("wrong token got %v", err)
+	}
+	if err := c.DeletePaste(u, r.DeleteToken); err != nil {
+		t.Fatal(err)
+	}
+	if s.Has(r.PasteID) {
+		t.Error("paste still there")
+	}
+	if _, err := c.OpenPaste(u); !errors.Is(err, pbin.ErrNotFound) {
+		t.Errorf("deleted paste got %v", err)
+	}
+}
+
+func TestBurnAfterReading(t *testing.T) {
+	s, c := setup(t)
+	p, _ := c.CraftPaste([]byte("once"))
+	p.BurnAfterRead(true)
+	r, u := send(t, p)
+	got, err := c.OpenPaste(u)
+	if err != nil || string(got.Text()) != "once" {
+		t.Fatalf("got %v", err)
+	}
+	if s.Has(r.PasteID) {
+		t.Error("paste not burnt")
+	}
+	if _, err = c.OpenPaste(u); !errors.Is(err, pbin.ErrNotFound) {
+		t.Errorf("second read got %v", err)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	s, c := setup(t)
+	p, _ := c.CraftPaste([]byte("short lived"))
+	p.SetExpiry("hour")
+	r, u := send(t, p)
+	keep, _ := c.CraftPaste([]byte("forever"))
+	keep.SetExpiry("never")
+	_, ku := send(t, keep)
+	s.Advance(59 * time.Minute)
+	if !s.Has(r.PasteID) {
+		t.Fatal("expired too early")
+	}
+	s.Advance(2 * time.Minute)
+	if s.Has(r.PasteID) || s.Len() != 1 {
+		t.Fatal("paste did not expire")
+	}
+	if _, err := c.OpenPaste(u); !errors.Is(err, pbin.ErrNotFound) {
+		t.Errorf("expired paste got %v", err)
+	}
+	if _, err := c.OpenPaste(ku); err != nil {
+		t.Errorf("never expiring paste got %v", err)
+	}
+}
+
+func TestInfo(t *testing.T) {
+	_, c := setup(t)
+	p, _ := c.CraftPaste([]byte("inspect me"))
+	p.SetExpiry("day")
+	_, u := send(t, p)
+	pi, err := c.Info(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pi.TTL <= 23*3600 || pi.TTL > 24*3600 || pi.Expires == nil || pi.Created == nil || pi.NeedsPassword {
+		t.Errorf("info %+v", pi)
+	}
+	p, _ = c.CraftPaste([]byte("never"))
+	p.SetExpiry("never")
+	_, u = send(t, p)
+	pi, err = c.Info(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(pi)
+	if pi.Expires != nil || strings.Contains(string(b), `"expires"`) || strings.Contains(string(b), `"ttl"`) {
+		t.Errorf("never expiring paste: %s", b)
+	}
+}
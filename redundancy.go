@@ -0,0 +1,142 @@
+package pbin
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// SetCopies makes Send upload the paste to n distinct hosts, so it outlives any one of them.
+// Every copy is encrypted with its own key, the result of the first host carries the
+// others in Copies. Hosts are picked like for a single paste. With SetHost the other
+// copies only go to hosts added with AddHost or from a config file, Send fails before
+// uploading anything when not enough of them answer
+func (p *Paste) SetCopies(n int) {
+	p.copies = n
+}
+
+func (p *Paste) skips(api string) bool {
+	for _, s := range p.skipHosts {
+		if s == api {
+			return true
+		}
+	}
+	return false
+}
+
+// registeredHosts ranks the hosts for the copies of a paste sent with SetHost,
+// a chosen host never leads to a public one
+func (p *Paste) registeredHosts(ctx context.Context) ([]*host, error) {
+	hsts := rankHosts(ctx, p.getClient().dialer(), p.usableHosts(func(h *host) bool {
+		return !h.builtin && h.api.String() != p.host.api.String()
+	}))
+	if len(hsts) < p.copies-1 {
+		return nil, fmt.Errorf("%w: %d copies need %d registered hosts besides %s, %d answered", ErrNoHost, p.copies, p.copies-1, p.host.api, len(hsts))
+	}
+	return hsts, nil
+}
+
+// sendCopies uploads the copies after the first one along hosts, ranked by Send,
+// skipping the ones already used or tried
+func (p *Paste) sendCopies(ctx context.Context, r *SendResult, hosts []*host) error {
+	used := map[string]bool{r.Host: true}
+	for _, a := range r.Attempts {
+		used[a.Host] = true
+	}
+	for len(r.Copies)+1 < p.copies {
+		left := []*host{}
+		for _, h := range hosts {
+			if !used[h.api.String()] {
+				left = append(left, h)
+			}
+		}
+		if len(left) == 0 {
+			return fmt.Errorf("%w: %d of %d copies sent", ErrNoHost, len(r.Copies)+1, p.copies)
+		}
+		q := p.clone(false)
+		q.shorten = p.shorten
+		q.maxAttempts = p.maxAttempts
+		cr, err := q.sendTo(ctx, left)
+		for _, a := range q.attempts {
+			used[a.Host] = true
+		}
+		if cr != nil {
+			r.Copies = append(r.Copies, cr)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %d of %d copies sent", err, len(r.Copies)+1, p.copies)
+		}
+	}
+	return nil
+}
+
+// OpenBundle reads the first copy of a paste it can among urls, trying the fastest hosts
+// first. The urls are the copies Send made with SetCopies, each has its own key
+func OpenBundle(urls []*url.URL) (*Paste, error) {
+	return DefaultClient.OpenBundleWithPromptContext(context.Background(), urls, "", nil)
+}
+
+// OpenBundleWithPromptContext is OpenBundle with the password handling of OpenPasteWithPrompt,
+// prompt is asked at most once
+func OpenBundleWithPromptContext(ctx context.Context, urls []*url.URL, pass string, prompt func() (string, error)) (*Paste, error) {
+	return DefaultClient.OpenBundleWithPromptContext(ctx, urls, pass, prompt)
+}
+
+func (c *Client) OpenBundle(urls []*url.URL) (*Paste, error) {
+	return c.OpenBundleWithPromptContext(context.Background(), urls, "", nil)
+}
+
+func (c *Client) OpenBundleWithPromptContext(ctx context.Context, urls []*url.URL, pass string, prompt func() (string, error)) (*Paste, error) {
+	if len(urls) == 0 {
+		return nil, ErrInvalidURL
+	}
+	ask := prompt
+	if prompt != nil {
+		ask = func() (string, error) {
+			s, err := prompt()
+			pass = s
+			// asked once, a wrong password fails the other copies without asking again
+			ask = nil
+			return s, err
+		}
+	}
+	err := error(nil)
+	for _, u := range c.rankURLs(ctx, urls) {
+		p := (*Paste)(nil)
+		p, err = c.OpenPasteWithPromptContext(ctx, u, pass, ask)
+		if err == nil {
+			return p, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// rankURLs orders urls by the ping time of their host, the hosts that did not answer go last
+func (c *Client) rankURLs(ctx context.Context, urls []*url.URL) []*url.URL {
+	hsts := []*host{}
+	byHost := map[*host]*url.URL{}
+	for _, u := range urls {
+		hostAPI, _, _, err := splitPasteURL(u)
+		if err != nil {
+			continue
+		}
+		h := &host{api: hostAPI}
+		hsts = append(hsts, h)
+		byHost[h] = u
+	}
+	ranked := []*url.URL{}
+	seen := map[*url.URL]bool{}
+	for _, h := range rankHosts(ctx, c.dialer(), hsts) {
+		ranked = append(ranked, byHost[h])
+		seen[byHost[h]] = true
+	}
+	for _, u := range urls {
+		if !seen[u] {
+			ranked = append(ranked, u)
+		}
+	}
+	return ranked
+}
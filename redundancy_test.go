@@ -0,0 +1,107 @@
+package pbin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cbluth/pbin/server"
+)
+
+type testHost struct {
+	*httptest.Server
+	storage *server.Memory
+}
+
+func newTestHost(t *testing.T) *testHost {
+	st := server.NewMemory()
+	ts := httptest.NewServer(server.New(st))
+	t.Cleanup(ts.Close)
+	return &testHost{Server: ts, storage: st}
+}
+
+func (h *testHost) pastes(t *testing.T) int {
+	ids, err := h.storage.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(ids)
+}
+
+// copiesClient knows no host but the given ones and counts its pings
+func copiesClient(t *testing.T, pings *int32) *Client {
+	c := NewClient(nil)
+	err := c.ApplyConfig(&Config{Replace: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		atomic.AddInt32(pings, 1)
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	}
+	return c
+}
+
+func addTestHost(t *testing.T, c *Client, h *testHost, builtin bool) {
+	u, err := parseHostURL(h.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.hosts.addHost(&host{api: u, expiry: []Expiry{Hour, Day, Week, Month, Year, Never}, features: []Feature{Burn, Discussion}, builtin: builtin})
+}
+
+func TestCopiesRankHostsOnce(t *testing.T) {
+	pings := int32(0)
+	c := copiesClient(t, &pings)
+	hsts := []*testHost{newTestHost(t), newTestHost(t), newTestHost(t)}
+	for _, h := range hsts {
+		addTestHost(t, c, h, false)
+	}
+	p, _ := c.CraftPaste([]byte("three times"))
+	p.SetCopies(3)
+	r, err := p.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Copies) != 2 {
+		t.Fatalf("%d copies", len(r.Copies))
+	}
+	for _, h := range hsts {
+		if h.pastes(t) != 1 {
+			t.Errorf("%s has %d pastes", h.URL, h.pastes(t))
+		}
+	}
+	if pings != 3 {
+		t.Errorf("%d pings for 3 hosts", pings)
+	}
+}
+
+func TestCopiesWithSetHost(t *testing.T) {
+	pings := int32(0)
+	c := copiesClient(t, &pings)
+	chosen, public, added := newTestHost(t), newTestHost(t), newTestHost(t)
+	addTestHost(t, c, public, true)
+	p, _ := c.CraftPaste([]byte("not for the public"))
+	p.SetHost(chosen.URL + "/")
+	p.SetCopies(2)
+	if _, err := p.Send(); !errors.Is(err, ErrNoHost) {
+		t.Fatalf("got %v, want ErrNoHost", err)
+	}
+	if chosen.pastes(t) != 0 || public.pastes(t) != 0 {
+		t.Fatal("uploaded before failing")
+	}
+	addTestHost(t, c, added, false)
+	r, err := p.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Host != chosen.URL+"/" || len(r.Copies) != 1 || r.Copies[0].Host != added.URL+"/" {
+		t.Fatalf("sent to %s, copies %v", r.Host, r.Copies)
+	}
+	if public.pastes(t) != 0 {
+		t.Error("a copy went to a public host")
+	}
+}
@@ -0,0 +1,27 @@
+package pbin
+
+import (
+	"net/url"
+)
+
+type (
+	// SendResult describes an uploaded paste
+	SendResult struct {
+		PasteID     string    `json:"id"`
+		URL         string    `json:"url"` // with the key in the fragment
+		ShortURL    string    `json:"shorturl,omitempty"`
+		DeleteToken string    `json:"deletetoken,omitempty"`
+		DeleteURL   string    `json:"deleteurl,omitempty"`
+		Host        string    `json:"host"`
+		Expiry      Expiry    `json:"expiry"`
+		Features    []Feature `json:"features"`
+		Attempts    []Attempt `json:"-"` // hosts tried before and including Host
+		// the other hosts with SetCopies, each copy has its own key and delete token
+		Copies []*SendResult `json:"copies,omitempty"`
+	}
+)
+
+// PasteURL is URL parsed, for GetPaste and friends
+func (r *SendResult) PasteURL() (*url.URL, error) {
+	return url.Parse(r.URL)
+}
@@ -0,0 +1,42 @@
+package pbin
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSendResultJSON(t *testing.T) {
+	pings := int32(0)
+	c := copiesClient(t, &pings)
+	h := newTestHost(t)
+	addTestHost(t, c, h, false)
+	p, _ := c.CraftPaste([]byte("typed"))
+	r, err := p.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := map[string]interface{}{}
+	json.Unmarshal(b, &m)
+	for _, k := range []string{"id", "url", "deletetoken", "deleteurl", "host", "expiry", "features"} {
+		if _, ok := m[k]; !ok {
+			t.Errorf("%s missing in %s", k, b)
+		}
+	}
+	// no short link was asked for and there are no copies
+	for _, k := range []string{"shorturl", "copies", "Attempts"} {
+		if _, ok := m[k]; ok {
+			t.Errorf("%s in %s", k, b)
+		}
+	}
+	u, err := r.PasteURL()
+	if err != nil || u.RawQuery != r.PasteID || u.Fragment == "" {
+		t.Fatalf("paste url %v, %v", u, err)
+	}
+	if got, err := c.GetPaste(u); err != nil || string(got) != "typed" {
+		t.Errorf("got %q, %v", got, err)
+	}
+}
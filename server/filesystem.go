@@ -0,0 +1,161 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type (
+	// Filesystem is a Storage of json files, spread over two directory levels
+	// like privatebin does: dir/ab/cd/abcd....json, comments in abcd....discussion/
+	Filesystem struct {
+		Dir string
+	}
+)
+
+// NewFilesystem creates dir when it does not exist, only the owner may read it
+func NewFilesystem(dir string) (*Filesystem, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	return &Filesystem{Dir: dir}, nil
+}
+
+// path expects an id checked by the server, 16 hex digits
+func (fs *Filesystem) path(id string) string {
+	return filepath.Join(fs.Dir, id[:2], id[2:4], id)
+}
+
+func (fs *Filesystem) Create(p *Paste) error {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	path := fs.path(p.ID)
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	return writeNew(path+".json", b)
+}
+
+func (fs *Filesystem) Read(id string) (*Paste, error) {
+	b, err := ioutil.ReadFile(fs.path(id) + ".json")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	p := &Paste{}
+	err = json.Unmarshal(b, p)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (fs *Filesystem) Delete(id string) error {
+	path := fs.path(id)
+	err := os.Remove(path + ".json")
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(path + ".discussion")
+}
+
+func (fs *Filesystem) AddComment(c *Comment) error {
+	path := fs.path(c.PasteID)
+	if _, err := os.Stat(path + ".json"); err != nil {
+		return ErrNotFound
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(path+".discussion", 0700)
+	if err != nil {
+		return err
+	}
+	return writeNew(filepath.Join(path+".discussion", c.ID+".json"), b)
+}
+
+func (fs *Filesystem) Comments(pasteID string) ([]*Comment, error) {
+	files, err := ioutil.ReadDir(fs.path(pasteID) + ".discussion")
+	if errors.Is(err, os.ErrNotExist) {
+		return []*Comment{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cs := []*Comment{}
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(fs.path(pasteID)+".discussion", f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		c := &Comment{}
+		err = json.Unmarshal(b, c)
+		if err != nil {
+			return nil, err
+		}
+		cs = append(cs, c)
+	}
+	sort.SliceStable(cs, func(i, j int) bool {
+		return cs[i].Created.Before(cs[j].Created)
+	})
+	return cs, nil
+}
+
+func (fs *Filesystem) List() ([]string, error) {
+	ids := []string{}
+	err := filepath.Walk(fs.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && strings.HasSuffix(info.Name(), ".discussion") {
+			return filepath.SkipDir
+		}
+		// other json files may sit in the directory
+		id := strings.TrimSuffix(info.Name(), ".json")
+		if !info.IsDir() && strings.HasSuffix(info.Name(), ".json") && idPattern.MatchString(id) {
+			ids = append(ids, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// writeNew fails with ErrExists instead of overwriting path
+func writeNew(path string, b []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return ErrExists
+	}
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
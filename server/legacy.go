@@ -0,0 +1,135 @@
+package server
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// legacyReply is the answer of privatebin 1.x to a read of a version 1 paste
+func (s *Server) legacyReply(p *Paste, cs []*Comment) reply {
+	meta := reply{
+		"formatter":        p.Formatter,
+		"postdate":         p.Created.Unix(),
+		"opendiscussion":   p.Discussion,
+		"burnafterreading": p.Burn,
+	}
+	if !p.Expires.IsZero() {
+		meta["remaining_time"] = int64(p.Expires.Sub(s.now()).Seconds())
+	}
+	comments := []reply{}
+	for _, c := range cs {
+		cm := reply{
+			"postdate": c.Created.Unix(),
+		}
+		if c.Nickname != "" {
+			cm["nickname"] = c.Nickname
+		}
+		comments = append(comments, reply{
+			"id":       c.ID,
+			"parentid": c.ParentID,
+			"data":     c.Data,
+			"meta":     cm,
+		})
+	}
+	rep := reply{
+		"status":         0,
+		"id":             p.ID,
+		"url":            s.prefix() + "?" + p.ID,
+		"data":           p.Data,
+		"meta":           meta,
+		"comments":       comments,
+		"comment_count":  len(comments),
+		"comment_offset": 0,
+	}
+	if p.Attachment != "" {
+		rep["attachment"] = p.Attachment
+		rep["attachmentname"] = p.AttachmentName
+	}
+	return rep
+}
+
+// createLegacy takes the form a privatebin 1.x client posts. Only comments on
+// version 1 pastes are stored, new pastes have to use version 2. s.mu must be held
+func (s *Server) createLegacy(w http.ResponseWriter, b []byte) {
+	form, err := url.ParseQuery(string(b))
+	if err != nil {
+		writeReply(w, fail(MsgInvalidData))
+		return
+	}
+	pasteID, parentID := form.Get("pasteid"), form.Get("parentid")
+	data, nickname := form.Get("data"), form.Get("nickname")
+	if pasteID == "" || !isSJCL(data) || (nickname != "" && !isSJCL(nickname)) || !idPattern.MatchString(parentID) {
+		writeReply(w, fail(MsgInvalidData))
+		return
+	}
+	if s.MaxSize > 0 && int64(len(data)) > s.MaxSize {
+		writeReply(w, fail(s.sizeMessage()))
+		return
+	}
+	p, err := s.get(pasteID)
+	if errors.Is(err, ErrNotFound) {
+		writeReply(w, fail(MsgNotFound))
+		return
+	}
+	if err != nil {
+		writeReply(w, fail(MsgServerError))
+		return
+	}
+	if p.V != 1 {
+		writeReply(w, fail(MsgInvalidData))
+		return
+	}
+	if !p.Discussion {
+		writeReply(w, fail(MsgNoDiscussion))
+		return
+	}
+	cs, err := s.Storage.Comments(pasteID)
+	if err != nil {
+		writeReply(w, fail(MsgServerError))
+		return
+	}
+	parentOK := parentID == pasteID
+	for _, c := range cs {
+		if c.ID == parentID {
+			parentOK = true
+			break
+		}
+	}
+	if !parentOK {
+		writeReply(w, fail(MsgInvalidData))
+		return
+	}
+	c := &Comment{
+		ID:       hex.EncodeToString(randomBytes(8)),
+		PasteID:  pasteID,
+		ParentID: parentID,
+		V:        1,
+		Data:     data,
+		Nickname: nickname,
+		Created:  s.now().UTC().Truncate(time.Second),
+	}
+	err = s.Storage.AddComment(c)
+	if err != nil {
+		writeReply(w, fail(MsgServerError))
+		return
+	}
+	writeReply(w, reply{
+		"status": 0,
+		"id":     c.ID,
+	})
+}
+
+// isSJCL checks that s is the json object sjcl encrypts to, with a base64 ct
+func isSJCL(s string) bool {
+	v := map[string]interface{}{}
+	if json.Unmarshal([]byte(s), &v) != nil {
+		return false
+	}
+	ct, _ := v["ct"].(string)
+	iv, _ := v["iv"].(string)
+	return isBase64(ct) && isBase64(iv)
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// limiter remembers the last post of every ip for the traffic limit
+	limiter struct {
+		last    map[string]time.Time
+		pruneAt int // size of last that prunes it on the next post
+		sync.Mutex
+	}
+)
+
+// wait returns how long ip still has to wait, or records the post and returns 0
+func (l *limiter) wait(ip string, now time.Time, every time.Duration) time.Duration {
+	l.Lock()
+	defer l.Unlock()
+	if l.last == nil {
+		l.last = map[string]time.Time{}
+	}
+	if t, ok := l.last[ip]; ok && now.Sub(t) < every {
+		return every - now.Sub(t)
+	}
+	l.last[ip] = now
+	// the purger prunes too, this only keeps a server without one from growing
+	if len(l.last) >= l.pruneAt {
+		l.drop(now, every)
+		l.pruneAt = 2*len(l.last) + 1024
+	}
+	return 0
+}
+
+// prune forgets the ips that may post again
+func (l *limiter) prune(now time.Time, every time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+	l.drop(now, every)
+}
+
+func (l *limiter) drop(now time.Time, every time.Duration) {
+	for k, t := range l.last {
+		if now.Sub(t) >= every {
+			delete(l.last, k)
+		}
+	}
+}
+
+// clientIP is the remote address, or the last address of IPHeader behind a proxy,
+// the ones before it come from the client
+func (s *Server) clientIP(r *http.Request) string {
+	if s.IPHeader != "" {
+		if v := r.Header.Values(s.IPHeader); len(v) > 0 {
+			ips := strings.Split(v[len(v)-1], ",")
+			return strings.TrimSpace(ips[len(ips)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// limitPost answers with the privatebin traffic limiter message when r comes too soon
+func (s *Server) limitPost(w http.ResponseWriter, r *http.Request) bool {
+	if s.RateLimit <= 0 {
+		return false
+	}
+	wait := s.limiter.wait(s.clientIP(r), s.now(), s.RateLimit)
+	if wait <= 0 {
+		return false
+	}
+	secs := int((s.RateLimit + time.Second - 1) / time.Second)
+	writeReply(w, fail("Please wait "+strconv.Itoa(secs)+" seconds between each post."))
+	return true
+}
+
+// Purge deletes the expired pastes with their comments and returns how many went,
+// the traffic limiter forgets the addresses that may post again
+func (s *Server) Purge() (int, error) {
+	if s.RateLimit > 0 {
+		s.limiter.prune(s.now(), s.RateLimit)
+	}
+	s.mu.Lock()
+	ids, err := s.Storage.List()
+	s.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, id := range ids {
+		// storage paths are built from checked ids only
+		if !idPattern.MatchString(id) {
+			continue
+		}
+		// the lock is taken per paste to keep serving during a long purge
+		s.mu.Lock()
+		p, err := s.Storage.Read(id)
+		if err == nil && !p.Expires.IsZero() && !s.now().Before(p.Expires) {
+			err = s.Storage.Delete(id)
+			if err == nil {
+				n++
+			}
+		}
+		s.mu.Unlock()
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// RunPurger calls Purge every interval until ctx is done, errors go to logf when set
+func (s *Server) RunPurger(ctx context.Context, interval time.Duration, logf func(format string, v ...interface{})) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			{
+				return
+			}
+		case <-t.C:
+			{
+				_, err := s.Purge()
+				if err != nil && logf != nil {
+					logf("purge: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// humanSize formats n like the privatebin size limit message
+func humanSize(n int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	f := float64(n)
+	i := 0
+	for f >= 1024 && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+	return strconv.FormatFloat(math.Round(f*10)/10, 'f', -1, 64) + " " + units[i]
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPurgeSkipsStrayFiles(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := NewFilesystem(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	s := New(fs)
+	s.Now = func() time.Time { return now }
+	for id, expires := range map[string]time.Time{
+		"00112233aabbccdd": now.Add(-time.Minute),
+		"44556677aabbccdd": now.Add(time.Hour),
+		"8899aabbccddeeff": {},
+	} {
+		err = fs.Create(&Paste{ID: id, V: 2, Created: now.Add(-time.Hour), Expires: expires})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// files a paste id can not be made of
+	for _, name := range []string{"ab.json", "00/11/x.json", "config.json"} {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0700)
+		ioutil.WriteFile(filepath.Join(dir, name), []byte("{}"), 0600)
+	}
+	ids, err := fs.List()
+	if err != nil || len(ids) != 3 {
+		t.Fatalf("listed %v, %v", ids, err)
+	}
+	n, err := s.Purge()
+	if err != nil || n != 1 {
+		t.Fatalf("purged %d, %v", n, err)
+	}
+	if _, err = fs.Read("00112233aabbccdd"); err != ErrNotFound {
+		t.Errorf("expired paste still there: %v", err)
+	}
+	if _, err = fs.Read("8899aabbccddeeff"); err != nil {
+		t.Errorf("never expiring paste gone: %v", err)
+	}
+}
+
+func TestClientIPTakesTheProxyAddress(t *testing.T) {
+	s := New(NewMemory())
+	s.IPHeader = "X-Forwarded-For"
+	s.RateLimit = time.Minute
+	post := func(forged string) bool {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Add("X-Forwarded-For", forged+", 198.51.100.7")
+		return s.limitPost(httptest.NewRecorder(), r)
+	}
+	if post("10.0.0.1") {
+		t.Fatal("first post limited")
+	}
+	// a new forged address in front does not reset the limit
+	if !post("10.0.0.2") {
+		t.Fatal("forged address got around the limit")
+	}
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	if s.clientIP(r) != "192.0.2.1" {
+		t.Errorf("without the header got %s", s.clientIP(r))
+	}
+}
+
+func TestLimiterPrunes(t *testing.T) {
+	now := time.Now()
+	s := New(NewMemory())
+	s.Now = func() time.Time { return now }
+	s.RateLimit = time.Minute
+	l := &s.limiter
+	for i := 0; i < 100; i++ {
+		l.wait("192.0.2."+strconv.Itoa(i), now, s.RateLimit)
+	}
+	now = now.Add(2 * time.Minute)
+	// posts do not scan the map until it has grown
+	l.wait("192.0.2.200", now, s.RateLimit)
+	if len(l.last) != 101 {
+		t.Fatalf("%d addresses kept", len(l.last))
+	}
+	if _, err := s.Purge(); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.last) != 1 {
+		t.Errorf("%d addresses kept after purge", len(l.last))
+	}
+	if l.wait("192.0.2.200", now, s.RateLimit) <= 0 {
+		t.Error("the address that may not post yet was forgotten")
+	}
+}
@@ -0,0 +1,355 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// first line of every file privatebin writes, keeps the web server from serving them
+	protectionLine = "<?php http_response_code(403); /*"
+)
+
+type (
+	// PrivateBinDir is a Storage on the data directory of the php privatebin,
+	// so a host can move to this server without losing its pastes and links:
+	// dir/ab/cd/abcd....php, comments in abcd....discussion/PASTEID.COMMENTID.PARENTID.php.
+	// Files without the .php extension, from before privatebin 1.3, are read too.
+	// Version 1 pastes and comments are served as privatebin 1.x did, their delete token
+	// comes from the salt of the paste or, for older ones, of dir/salt.php
+	PrivateBinDir struct {
+		Dir string
+	}
+	// pbFile is a paste or comment file, v1 ones keep their sjcl json in Data
+	pbFile struct {
+		V              int             `json:"v,omitempty"`
+		AData          json.RawMessage `json:"adata,omitempty"`
+		CT             string          `json:"ct,omitempty"`
+		Data           string          `json:"data,omitempty"`
+		Meta           pbMeta          `json:"meta"`
+		Attachment     string          `json:"attachment,omitempty"`
+		AttachmentName string          `json:"attachmentname,omitempty"`
+	}
+	pbMeta struct {
+		Created        int64       `json:"created,omitempty"`
+		PostDate       int64       `json:"postdate,omitempty"`
+		ExpireDate     int64       `json:"expire_date,omitempty"`
+		Salt           string      `json:"salt,omitempty"`
+		Burn           interface{} `json:"burnafterreading,omitempty"`
+		Discussion     interface{} `json:"opendiscussion,omitempty"`
+		Formatter      string      `json:"formatter,omitempty"`
+		SyntaxColoring interface{} `json:"syntaxcoloring,omitempty"`
+		Attachment     string      `json:"attachment,omitempty"`
+		AttachmentName string      `json:"attachmentname,omitempty"`
+		Nickname       string      `json:"nickname,omitempty"`
+	}
+)
+
+// NewPrivateBinDir checks that dir exists, it is not created to catch a wrong path
+func NewPrivateBinDir(dir string) (*PrivateBinDir, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, errors.New("not a directory: " + dir)
+	}
+	return &PrivateBinDir{Dir: dir}, nil
+}
+
+// path expects an id checked by the server, 16 hex digits
+func (pb *PrivateBinDir) path(id string) string {
+	return filepath.Join(pb.Dir, id[:2], id[2:4], id)
+}
+
+func (pb *PrivateBinDir) Create(p *Paste) error {
+	path := pb.path(p.ID)
+	if pb.exists(path) {
+		return ErrExists
+	}
+	f := &pbFile{
+		V:     p.V,
+		AData: p.AData,
+		CT:    p.CT,
+		Meta: pbMeta{
+			Created: p.Created.Unix(),
+			Salt:    p.Salt,
+		},
+	}
+	if !p.Expires.IsZero() {
+		f.Meta.ExpireDate = p.Expires.Unix()
+	}
+	b, err := encodePHP(f)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	return writeNew(path+".php", b)
+}
+
+func (pb *PrivateBinDir) Read(id string) (*Paste, error) {
+	f := &pbFile{}
+	err := readPHP(pb.path(id), f)
+	if err != nil {
+		return nil, err
+	}
+	p := &Paste{
+		ID:   id,
+		V:    f.V,
+		Salt: f.Meta.Salt,
+	}
+	if f.Meta.ExpireDate > 0 {
+		p.Expires = time.Unix(f.Meta.ExpireDate, 0).UTC()
+	}
+	if f.V == 0 {
+		// version 1 keeps its settings in meta
+		p.V = 1
+		p.Data = f.Data
+		p.Created = time.Unix(f.Meta.PostDate, 0).UTC()
+		p.Burn = phpBool(f.Meta.Burn)
+		p.Discussion = phpBool(f.Meta.Discussion)
+		p.Formatter = f.Meta.Formatter
+		if p.Formatter == "" {
+			// zerobin and the first privatebin releases only had a switch
+			p.Formatter = "plaintext"
+			if phpBool(f.Meta.SyntaxColoring) {
+				p.Formatter = "syntaxhighlighting"
+			}
+		}
+		// stored in meta, some releases moved them up
+		p.Attachment, p.AttachmentName = f.Meta.Attachment, f.Meta.AttachmentName
+		if p.Attachment == "" {
+			p.Attachment, p.AttachmentName = f.Attachment, f.AttachmentName
+		}
+		if p.Salt == "" {
+			p.Salt, err = pb.serverSalt()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return p, nil
+	}
+	p.AData = f.AData
+	p.CT = f.CT
+	p.Created = time.Unix(f.Meta.Created, 0).UTC()
+	adata := []json.RawMessage{}
+	if json.Unmarshal(f.AData, &adata) == nil && len(adata) == 4 {
+		discussion, burn := 0, 0
+		json.Unmarshal(adata[2], &discussion)
+		json.Unmarshal(adata[3], &burn)
+		p.Burn = burn == 1
+		p.Discussion = discussion == 1 && burn != 1
+	}
+	return p, nil
+}
+
+func (pb *PrivateBinDir) Delete(id string) error {
+	path := pb.path(id)
+	found := false
+	for _, name := range []string{path + ".php", path} {
+		err := os.Remove(name)
+		if err == nil {
+			found = true
+			continue
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return os.RemoveAll(path + ".discussion")
+}
+
+func (pb *PrivateBinDir) AddComment(c *Comment) error {
+	path := pb.path(c.PasteID)
+	if !pb.exists(path) {
+		return ErrNotFound
+	}
+	f := &pbFile{
+		V:     c.V,
+		AData: c.AData,
+		CT:    c.CT,
+		Meta: pbMeta{
+			Created: c.Created.Unix(),
+		},
+	}
+	if c.V == 1 {
+		f = &pbFile{
+			Data: c.Data,
+			Meta: pbMeta{
+				PostDate: c.Created.Unix(),
+				Nickname: c.Nickname,
+			},
+		}
+	}
+	b, err := encodePHP(f)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(path+".discussion", 0700)
+	if err != nil {
+		return err
+	}
+	return writeNew(filepath.Join(path+".discussion", c.PasteID+"."+c.ID+"."+c.ParentID+".php"), b)
+}
+
+func (pb *PrivateBinDir) Comments(pasteID string) ([]*Comment, error) {
+	dir := pb.path(pasteID) + ".discussion"
+	files, err := ioutil.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []*Comment{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cs := []*Comment{}
+	for _, fi := range files {
+		// PASTEID.COMMENTID.PARENTID with an optional .php
+		ids := strings.Split(strings.TrimSuffix(fi.Name(), ".php"), ".")
+		if len(ids) != 3 || ids[0] != pasteID || !idPattern.MatchString(ids[1]) || !idPattern.MatchString(ids[2]) {
+			continue
+		}
+		f := &pbFile{}
+		err = readPHP(filepath.Join(dir, strings.TrimSuffix(fi.Name(), ".php")), f)
+		if err != nil {
+			return nil, err
+		}
+		c := &Comment{
+			ID:       ids[1],
+			PasteID:  pasteID,
+			ParentID: ids[2],
+			V:        f.V,
+			AData:    f.AData,
+			CT:       f.CT,
+			Created:  time.Unix(f.Meta.Created, 0).UTC(),
+		}
+		if f.V == 0 {
+			c.V = 1
+			c.Data = f.Data
+			c.Nickname = f.Meta.Nickname
+			c.Created = time.Unix(f.Meta.PostDate, 0).UTC()
+		}
+		cs = append(cs, c)
+	}
+	sort.SliceStable(cs, func(i, j int) bool {
+		return cs[i].Created.Before(cs[j].Created)
+	})
+	return cs, nil
+}
+
+func (pb *PrivateBinDir) List() ([]string, error) {
+	ids := []string{}
+	seen := map[string]bool{}
+	err := filepath.Walk(pb.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && strings.HasSuffix(info.Name(), ".discussion") {
+			return filepath.SkipDir
+		}
+		// salt.php, traffic_limiter.php and the like sit next to the pastes
+		id := strings.TrimSuffix(info.Name(), ".php")
+		if !info.IsDir() && idPattern.MatchString(id) && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// serverSalt is the salt of the whole host, privatebin before 1.3 wrote it as
+// <?php /* |SALT| */ ?> and later the protection line followed by the salt
+func (pb *PrivateBinDir) serverSalt() (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(pb.Dir, "salt.php"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	s := string(b)
+	if i, j := strings.Index(s, "|"), strings.LastIndex(s, "|"); i >= 0 && j > i {
+		return s[i+1 : j], nil
+	}
+	if i := strings.IndexByte(s, '\n'); strings.HasPrefix(s, "<?php") && i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.Trim(s, "\" \r\n\t"), nil
+}
+
+func (pb *PrivateBinDir) exists(path string) bool {
+	for _, name := range []string{path + ".php", path} {
+		if _, err := os.Stat(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// readPHP reads path.php, or path as written before privatebin 1.3
+func readPHP(path string, v interface{}) error {
+	b, err := ioutil.ReadFile(path + ".php")
+	if errors.Is(err, os.ErrNotExist) {
+		b, err = ioutil.ReadFile(path)
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if bytes.HasPrefix(b, []byte("<?php")) {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			return errors.New("truncated file: " + path)
+		}
+		b = b[i+1:]
+	}
+	return json.Unmarshal(b, v)
+}
+
+func encodePHP(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(protectionLine+"\n"), b...), nil
+}
+
+// phpBool reads the loose booleans of the v1 meta: true, 1 or "1"
+func phpBool(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		{
+			return b
+		}
+	case float64:
+		{
+			return b != 0
+		}
+	case string:
+		{
+			n, err := strconv.Atoi(b)
+			return b == "true" || (err == nil && n != 0)
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package server_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cbluth/pbin"
+	"github.com/cbluth/pbin/server"
+)
+
+const (
+	// the paste of ../testdata/privatebin1.json
+	legacyKey  = "5eE+CjyJI3rtE52ufXF580upy3D3xga+GxFokr7L1Ic="
+	legacyPass = "hunter2"
+	serverSalt = "0f1e2d3c4b5a6978"
+)
+
+// writeLegacyPaste stores the fixture as privatebin before 1.3 did, without extension
+func writeLegacyPaste(t *testing.T, dir, id string, burn bool) {
+	b, err := ioutil.ReadFile(filepath.Join("..", "testdata", "privatebin1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fx := struct {
+		Data           string `json:"data"`
+		Attachment     string `json:"attachment"`
+		AttachmentName string `json:"attachmentname"`
+	}{}
+	err = json.Unmarshal(b, &fx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = json.Marshal(map[string]interface{}{
+		"data": fx.Data,
+		"meta": map[string]interface{}{
+			"postdate":         1500000000,
+			"opendiscussion":   !burn,
+			"burnafterreading": burn,
+			"formatter":        "markdown",
+			"attachment":       fx.Attachment,
+			"attachmentname":   fx.AttachmentName,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll(filepath.Join(dir, id[:2], id[2:4]), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, id[:2], id[2:4], id), b, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrivateBinDirVersion1(t *testing.T) {
+	dir := t.TempDir()
+	writeLegacyPaste(t, dir, "7d1a9e3c5b2f4a60", false)
+	writeLegacyPaste(t, dir, "8e2b0f4d6c305b71", true)
+	err := ioutil.WriteFile(filepath.Join(dir, "salt.php"), []byte("<?php /* |"+serverSalt+"| */ ?>"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, err := server.NewPrivateBinDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server.New(pb))
+	defer ts.Close()
+	c := pbin.NewClient(ts.Client())
+	u, _ := url.Parse(ts.URL + "/?7d1a9e3c5b2f4a60#" + legacyKey)
+	open := func(u *url.URL) (*pbin.Paste, error) {
+		return c.OpenPasteWithPromptContext(context.Background(), u, legacyPass, nil)
+	}
+	p, err := open(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, att := p.Attachment()
+	if string(p.Text()) != "hello from privatebin 1.x" || p.Format() != pbin.FormatMarkdown || p.Version() != 1 || name != "notes.txt" || string(att) != "attached" {
+		t.Fatalf("got %q as %s, version %d, attachment %q %q", p.Text(), p.Format(), p.Version(), name, att)
+	}
+	// comments are posted as 1.x forms and kept in the discussion directory
+	cm, err := c.CraftComment(u, []byte("old but gold"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm.SetPassword(legacyPass)
+	cm.SetNickname("bob")
+	first, err := cm.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, _ := c.CraftComment(u, []byte("agreed"))
+	reply.SetPassword(legacyPass)
+	reply.ReplyTo(first)
+	if _, err = reply.Send(); err != nil {
+		t.Fatal(err)
+	}
+	p, err = open(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byText := map[string]*pbin.Comment{}
+	for _, cm := range p.Comments() {
+		byText[string(cm.Text())] = cm
+	}
+	// both were posted within the same second, their order is not fixed
+	if len(byText) != 2 || byText["old but gold"].Nickname() != "bob" || byText["agreed"].ParentID() != first {
+		t.Fatalf("comments %v", byText)
+	}
+	// the token of a paste without its own salt comes from salt.php
+	m := hmac.New(sha256.New, []byte(serverSalt))
+	m.Write([]byte("7d1a9e3c5b2f4a60"))
+	if err = c.DeletePaste(u, hex.EncodeToString(m.Sum(nil))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = open(u); !errors.Is(err, pbin.ErrNotFound) {
+		t.Errorf("deleted paste got %v", err)
+	}
+	// burnt on the first read
+	u, _ = url.Parse(ts.URL + "/?8e2b0f4d6c305b71#" + legacyKey)
+	if _, err = open(u); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = open(u); !errors.Is(err, pbin.ErrNotFound) {
+		t.Errorf("second read got %v", err)
+	}
+}
@@ -0,0 +1,575 @@
+// Package server is a privatebin v2 json api host, pastes are encrypted by
+// the clients and stored as they arrive through a pluggable Storage
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// the messages of the privatebin php code, clients match them
+	MsgNotFound     = "Paste does not exist, has expired or has been deleted."
+	MsgInvalidData  = "Invalid data."
+	MsgWrongToken   = "Wrong deletion token. Paste was not deleted."
+	MsgNoDiscussion = "Discussion is disabled for this paste."
+	MsgExists       = "You are unlucky. Try again."
+	MsgServerError  = "Error saving paste. Sorry."
+
+	defaultExpiry = 7 * 24 * time.Hour
+)
+
+var (
+	idPattern = regexp.MustCompile(`\A[0-9a-f]{16}\z`)
+)
+
+type (
+	// Server answers the privatebin api at Prefix, set the fields before serving.
+	// The limits are off when zero
+	Server struct {
+		Storage   Storage
+		Prefix    string           // path of the api, "/" when empty
+		Now       func() time.Time // time.Now when nil, tests move it
+		MaxSize   int64            // bytes of ciphertext of a paste or comment
+		MaxExpiry time.Duration    // longer expiries and never are refused
+		RateLimit time.Duration    // time one ip has to wait between two posts
+		// IPHeader holds the client ip behind a proxy, like X-Forwarded-For. The last
+		// address is taken, the one the proxy added, so only set it behind a proxy that appends
+		IPHeader string
+		mu       sync.Mutex
+		limiter  limiter
+	}
+	reply map[string]interface{}
+)
+
+// New serves st at the root path
+func New(st Storage) *Server {
+	return &Server{Storage: st, Prefix: "/"}
+}
+
+func (s *Server) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s *Server) prefix() string {
+	p := s.Prefix
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	if !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	return p
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	prefix := s.prefix()
+	if r.URL.Path != prefix {
+		if r.URL.Path+"/" == prefix {
+			http.Redirect(w, r, prefix, http.StatusMovedPermanently)
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		{
+			// read before locking, a slow client must not hold up the others
+			body := io.Reader(r.Body)
+			if s.MaxSize > 0 {
+				body = io.LimitReader(r.Body, s.maxBody()+1)
+			}
+			b, err := ioutil.ReadAll(body)
+			if err != nil {
+				writeReply(w, fail(MsgInvalidData))
+				return
+			}
+			if s.MaxSize > 0 && int64(len(b)) > s.maxBody() {
+				writeReply(w, fail(s.sizeMessage()))
+				return
+			}
+			// pbincli and others delete with a posted pasteid and deletetoken,
+			// like privatebin this is not held up by the traffic limit
+			if id, token, ok := deleteRequest(b); ok {
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				s.delete(w, id, token)
+				return
+			}
+			if s.limitPost(w, r) {
+				return
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			// privatebin 1.x clients post forms
+			if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+				s.createLegacy(w, b)
+				return
+			}
+			s.create(w, b)
+		}
+	case http.MethodGet:
+		{
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			q := r.URL.Query()
+			id := q.Get("pasteid")
+			if id == "" && !strings.ContainsAny(r.URL.RawQuery, "=&") {
+				id = r.URL.RawQuery
+			}
+			if id == "" {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.Write([]byte("privatebin api, use a privatebin client like pbin\n"))
+				return
+			}
+			if q.Get("deletetoken") != "" {
+				s.delete(w, id, q.Get("deletetoken"))
+				return
+			}
+			s.read(w, id)
+		}
+	case http.MethodDelete:
+		{
+			req := map[string]string{}
+			err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req)
+			if err != nil {
+				writeReply(w, fail(MsgInvalidData))
+				return
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.delete(w, req["pasteid"], req["deletetoken"])
+		}
+	default:
+		{
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// Paste returns the paste id when it exists and has not expired, without burning it
+func (s *Server) Paste(id string) (*Paste, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(id)
+}
+
+// get checks the id and drops the paste when it has expired, s.mu must be held
+func (s *Server) get(id string) (*Paste, error) {
+	if !idPattern.MatchString(id) {
+		return nil, ErrNotFound
+	}
+	p, err := s.Storage.Read(id)
+	if err != nil {
+		return nil, err
+	}
+	if !p.Expires.IsZero() && !s.now().Before(p.Expires) {
+		err = s.Storage.Delete(id)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		return nil, ErrNotFound
+	}
+	return p, nil
+}
+
+// deleteRequest reads a json body with pasteid and deletetoken but no ct
+func deleteRequest(b []byte) (string, string, bool) {
+	req := map[string]json.RawMessage{}
+	if json.Unmarshal(b, &req) != nil || req["ct"] != nil {
+		return "", "", false
+	}
+	id, token := "", ""
+	if json.Unmarshal(req["pasteid"], &id) != nil || json.Unmarshal(req["deletetoken"], &token) != nil {
+		return "", "", false
+	}
+	return id, token, true
+}
+
+// create takes a paste or a comment, s.mu must be held
+func (s *Server) create(w http.ResponseWriter, b []byte) {
+	req := map[string]json.RawMessage{}
+	err := json.Unmarshal(b, &req)
+	if err != nil {
+		writeReply(w, fail(MsgInvalidData))
+		return
+	}
+	v := 0
+	ct := ""
+	if json.Unmarshal(req["v"], &v) != nil || v != 2 || json.Unmarshal(req["ct"], &ct) != nil || !isBase64(ct) {
+		writeReply(w, fail(MsgInvalidData))
+		return
+	}
+	if s.MaxSize > 0 && int64(len(ct)) > s.MaxSize {
+		writeReply(w, fail(s.sizeMessage()))
+		return
+	}
+	if _, ok := req["pasteid"]; ok {
+		s.addComment(w, req, v, ct)
+		return
+	}
+	adata := []json.RawMessage{}
+	if json.Unmarshal(req["adata"], &adata) != nil || len(adata) != 4 || !isCipherSpec(adata[0]) {
+		writeReply(w, fail(MsgInvalidData))
+		return
+	}
+	discussion, burn := 0, 0
+	if json.Unmarshal(adata[2], &discussion) != nil || json.Unmarshal(adata[3], &burn) != nil {
+		writeReply(w, fail(MsgInvalidData))
+		return
+	}
+	meta := struct {
+		Expire string `json:"expire"`
+	}{}
+	if len(req["meta"]) > 0 && json.Unmarshal(req["meta"], &meta) != nil {
+		writeReply(w, fail(MsgInvalidData))
+		return
+	}
+	// the token is derived like privatebin does, storage may keep either the salt or the hash
+	id := hex.EncodeToString(randomBytes(8))
+	salt := hex.EncodeToString(randomBytes(32))
+	token := saltToken(id, salt)
+	p := &Paste{
+		ID:          id,
+		V:           v,
+		AData:       req["adata"],
+		CT:          ct,
+		Created:     s.now().UTC().Truncate(time.Second),
+		Burn:        burn == 1,
+		Discussion:  discussion == 1 && burn != 1,
+		DeleteToken: hashToken(token),
+		Salt:        salt,
+	}
+	d := ExpiryDuration(meta.Expire)
+	if s.MaxExpiry > 0 && (d == 0 || d > s.MaxExpiry) {
+		if meta.Expire != "" {
+			writeReply(w, fail("Expiry "+meta.Expire+" is not allowed on this host."))
+			return
+		}
+		d = s.MaxExpiry
+	}
+	if d > 0 {
+		p.Expires = p.Created.Add(d)
+	}
+	err = s.Storage.Create(p)
+	if errors.Is(err, ErrExists) {
+		writeReply(w, fail(MsgExists))
+		return
+	}
+	if err != nil {
+		writeReply(w, fail(MsgServerError))
+		return
+	}
+	writeReply(w, reply{
+		"status":      0,
+		"id":          p.ID,
+		"url":         s.prefix() + "?" + p.ID,
+		"deletetoken": token,
+	})
+}
+
+func (s *Server) addComment(w http.ResponseWriter, req map[string]json.RawMessage, v int, ct string) {
+	pasteID, parentID := "", ""
+	if json.Unmarshal(req["pasteid"], &pasteID) != nil || json.Unmarshal(req["parentid"], &parentID) != nil {
+		writeReply(w, fail(MsgInvalidData))
+		return
+	}
+	if !isCipherSpec(req["adata"]) || !idPattern.MatchString(parentID) {
+		writeReply(w, fail(MsgInvalidData))
+		return
+	}
+	p, err := s.get(pasteID)
+	if errors.Is(err, ErrNotFound) {
+		writeReply(w, fail(MsgNotFound))
+		return
+	}
+	if err != nil {
+		writeReply(w, fail(MsgServerError))
+		return
+	}
+	if p.V != 2 {
+		// a version 1 paste takes version 1 comments only
+		writeReply(w, fail(MsgInvalidData))
+		return
+	}
+	if !p.Discussion {
+		writeReply(w, fail(MsgNoDiscussion))
+		return
+	}
+	cs, err := s.Storage.Comments(pasteID)
+	if err != nil {
+		writeReply(w, fail(MsgServerError))
+		return
+	}
+	parentOK := parentID == pasteID
+	for _, c := range cs {
+		if c.ID == parentID {
+			parentOK = true
+			break
+		}
+	}
+	if !parentOK {
+		writeReply(w, fail(MsgInvalidData))
+		return
+	}
+	c := &Comment{
+		ID:       hex.EncodeToString(randomBytes(8)),
+		PasteID:  pasteID,
+		ParentID: parentID,
+		V:        v,
+		AData:    req["adata"],
+		CT:       ct,
+		Created:  s.now().UTC().Truncate(time.Second),
+	}
+	err = s.Storage.AddComment(c)
+	if err != nil {
+		writeReply(w, fail(MsgServerError))
+		return
+	}
+	writeReply(w, reply{
+		"status": 0,
+		"id":     c.ID,
+	})
+}
+
+func (s *Server) read(w http.ResponseWriter, id string) {
+	p, err := s.get(id)
+	if errors.Is(err, ErrNotFound) {
+		writeReply(w, fail(MsgNotFound))
+		return
+	}
+	if err != nil {
+		writeReply(w, fail(MsgServerError))
+		return
+	}
+	cs, err := s.Storage.Comments(id)
+	if err != nil {
+		writeReply(w, fail(MsgServerError))
+		return
+	}
+	rep := reply(nil)
+	if p.V == 1 {
+		rep = s.legacyReply(p, cs)
+	} else {
+		rep = s.pasteReply(p, cs)
+	}
+	if p.Burn {
+		// burnt on the first read, like privatebin does
+		err = s.Storage.Delete(id)
+		if err != nil {
+			writeReply(w, fail(MsgServerError))
+			return
+		}
+	}
+	writeReply(w, rep)
+}
+
+// pasteReply is the answer of the v2 api to a read
+func (s *Server) pasteReply(p *Paste, cs []*Comment) reply {
+	meta := reply{
+		"created": p.Created.Unix(),
+	}
+	if !p.Expires.IsZero() {
+		meta["time_to_live"] = int64(p.Expires.Sub(s.now()).Seconds())
+	}
+	comments := []reply{}
+	for _, c := range cs {
+		comments = append(comments, reply{
+			"id":       c.ID,
+			"pasteid":  c.PasteID,
+			"parentid": c.ParentID,
+			"v":        c.V,
+			"adata":    c.AData,
+			"ct":       c.CT,
+			"meta": reply{
+				"created": c.Created.Unix(),
+			},
+		})
+	}
+	return reply{
+		"status":         0,
+		"id":             p.ID,
+		"url":            s.prefix() + "?" + p.ID,
+		"v":              p.V,
+		"adata":          p.AData,
+		"ct":             p.CT,
+		"meta":           meta,
+		"comments":       comments,
+		"comment_count":  len(comments),
+		"comment_offset": 0,
+	}
+}
+
+func (s *Server) delete(w http.ResponseWriter, id string, token string) {
+	p, err := s.get(id)
+	if errors.Is(err, ErrNotFound) {
+		writeReply(w, fail(MsgNotFound))
+		return
+	}
+	if err != nil {
+		writeReply(w, fail(MsgServerError))
+		return
+	}
+	if !checkToken(p, token) {
+		writeReply(w, fail(MsgWrongToken))
+		return
+	}
+	err = s.Storage.Delete(id)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		writeReply(w, fail(MsgServerError))
+		return
+	}
+	writeReply(w, reply{
+		"status": 0,
+		"id":     id,
+		"url":    s.prefix() + "?" + id,
+	})
+}
+
+// isCipherSpec checks the shape of [iv, salt, iterations, key size, tag size, algorithm, mode, compression],
+// the values are up to the clients
+func isCipherSpec(b json.RawMessage) bool {
+	spec := []interface{}{}
+	if json.Unmarshal(b, &spec) != nil || len(spec) != 8 {
+		return false
+	}
+	for i, e := range spec {
+		switch i {
+		case 2, 3, 4:
+			{
+				if _, ok := e.(float64); !ok {
+					return false
+				}
+			}
+		default:
+			{
+				if _, ok := e.(string); !ok {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func isBase64(s string) bool {
+	if s == "" {
+		return false
+	}
+	_, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+	return err == nil
+}
+
+// maxBody leaves room for the json around the ciphertext
+func (s *Server) maxBody() int64 {
+	return s.MaxSize + 64*1024
+}
+
+func (s *Server) sizeMessage() string {
+	return "Paste is limited to " + humanSize(s.MaxSize) + " of encrypted data."
+}
+
+// ExpiryDuration maps the expire values of the api, 0 is never
+// and unknown values get the privatebin default of a week
+func ExpiryDuration(e string) time.Duration {
+	switch e {
+	case "5min":
+		{
+			return 5 * time.Minute
+		}
+	case "10min":
+		{
+			return 10 * time.Minute
+		}
+	case "1hour":
+		{
+			return time.Hour
+		}
+	case "1day":
+		{
+			return 24 * time.Hour
+		}
+	case "1week":
+		{
+			return 7 * 24 * time.Hour
+		}
+	case "1month":
+		{
+			return 30 * 24 * time.Hour
+		}
+	case "1year":
+		{
+			return 365 * 24 * time.Hour
+		}
+	case "never":
+		{
+			return 0
+		}
+	}
+	return defaultExpiry
+}
+
+func hashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+// saltToken is the delete token of privatebin 1.3 and later
+func saltToken(id, salt string) string {
+	m := hmac.New(sha256.New, []byte(salt))
+	m.Write([]byte(id))
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+func checkToken(p *Paste, token string) bool {
+	switch {
+	case p.DeleteToken != "":
+		{
+			return subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(p.DeleteToken)) == 1
+		}
+	case p.Salt != "":
+		{
+			return hmac.Equal([]byte(token), []byte(saltToken(p.ID, p.Salt)))
+		}
+	}
+	return false
+}
+
+func fail(msg string) reply {
+	return reply{
+		"status":  1,
+		"message": msg,
+	}
+}
+
+func writeReply(w http.ResponseWriter, r reply) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(r)
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
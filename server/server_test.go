@@ -0,0 +1,110 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// postJSON posts body to the api of ts and decodes the reply
+func postJSON(t *testing.T, ts *httptest.Server, path, body string) map[string]interface{} {
+	res, err := http.Post(ts.URL+path, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	m := map[string]interface{}{}
+	err = json.NewDecoder(res.Body).Decode(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+const testSpec = `["AQEBAQEBAQEBAQEB","AgICAgICAgI=",100000,256,128,"aes","gcm","zlib"]`
+
+func TestCreateValidates(t *testing.T) {
+	s := New(NewMemory())
+	s.MaxSize = 16
+	s.MaxExpiry = 24 * time.Hour
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	for _, tc := range []struct {
+		body, want string
+	}{
+		{`not json`, MsgInvalidData},
+		{`{"v":1,"ct":"AAAA","adata":[` + testSpec + `,"plaintext",0,0]}`, MsgInvalidData},
+		{`{"v":2,"ct":"not base64!","adata":[` + testSpec + `,"plaintext",0,0]}`, MsgInvalidData},
+		{`{"v":2,"ct":"AAAA","adata":[["short"],"plaintext",0,0]}`, MsgInvalidData},
+		{`{"v":2,"ct":"AAAA","adata":[` + testSpec + `,"plaintext",0]}`, MsgInvalidData},
+		{`{"v":2,"ct":"AAAA","adata":[` + testSpec + `,"plaintext","yes",0]}`, MsgInvalidData},
+		{`{"v":2,"ct":"` + strings.Repeat("A", 20) + `","adata":[` + testSpec + `,"plaintext",0,0]}`, "Paste is limited to 16 B of encrypted data."},
+		{`{"v":2,"ct":"AAAA","adata":[` + testSpec + `,"plaintext",0,0],"meta":{"expire":"1week"}}`, "Expiry 1week is not allowed on this host."},
+		{`{"v":2,"ct":"AAAA","adata":[` + testSpec + `,"plaintext",0,0],"meta":{"expire":"never"}}`, "Expiry never is not allowed on this host."},
+		{`{"v":2,"ct":"AAAA","adata":` + testSpec + `,"pasteid":"0011223344556677","parentid":"0011223344556677"}`, MsgNotFound},
+	} {
+		m := postJSON(t, ts, "/", tc.body)
+		if m["status"] != float64(1) || m["message"] != tc.want {
+			t.Errorf("%s: got %v, want %q", tc.body, m, tc.want)
+		}
+	}
+	// without an expiry the longest allowed one is taken
+	m := postJSON(t, ts, "/", `{"v":2,"ct":"AAAA","adata":[`+testSpec+`,"markdown",1,0]}`)
+	id, _ := m["id"].(string)
+	p, err := s.Paste(id)
+	if err != nil || !p.Discussion || p.Burn || p.Expires.Sub(p.Created) != 24*time.Hour {
+		t.Fatalf("created %v: %+v, %v", m, p, err)
+	}
+	if m["url"] != "/?"+id || m["deletetoken"] == "" {
+		t.Errorf("reply %v", m)
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	s := New(NewMemory())
+	s.Prefix = "/paste/"
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	m := postJSON(t, ts, "/paste/", `{"v":2,"ct":"AAAA","adata":[`+testSpec+`,"plaintext",0,0]}`)
+	if m["status"] != float64(0) || !strings.HasPrefix(m["url"].(string), "/paste/?") {
+		t.Fatalf("got %v", m)
+	}
+	res, err := http.Get(ts.URL + "/other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("outside the prefix got %d", res.StatusCode)
+	}
+}
+
+func TestDeleteWithJSONPost(t *testing.T) {
+	st := NewMemory()
+	s := New(st)
+	// deletes are not held up by the traffic limit
+	s.RateLimit = time.Hour
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	err := st.Create(&Paste{ID: "0011223344556677", V: 2, Created: time.Now(), DeleteToken: hashToken("secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := func(body string) map[string]interface{} {
+		return postJSON(t, ts, "/", body)
+	}
+	m := post(`{"pasteid":"0011223344556677","deletetoken":"wrong"}`)
+	if m["message"] != MsgWrongToken {
+		t.Fatalf("wrong token got %v", m)
+	}
+	m = post(`{"pasteid":"0011223344556677","deletetoken":"secret"}`)
+	if m["status"] != float64(0) || m["id"] != "0011223344556677" {
+		t.Fatalf("delete got %v", m)
+	}
+	if _, err = st.Read("0011223344556677"); err != ErrNotFound {
+		t.Errorf("paste still there: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("paste not found")
+	ErrExists   = errors.New("paste id already taken")
+)
+
+type (
+	// Storage keeps pastes and their comments, the server holds a lock around
+	// every call so implementations need not be safe for concurrent use
+	Storage interface {
+		Create(p *Paste) error                       // ErrExists when p.ID is taken
+		Read(id string) (*Paste, error)              // ErrNotFound when missing
+		Delete(id string) error                      // removes the comments too
+		AddComment(c *Comment) error                 // ErrNotFound when the paste is missing
+		Comments(pasteID string) ([]*Comment, error) // oldest first
+		List() ([]string, error)                     // every paste id
+	}
+	// Paste is a stored paste, the server never sees its key
+	Paste struct {
+		ID          string          `json:"id"`
+		V           int             `json:"v"`
+		AData       json.RawMessage `json:"adata"`
+		CT          string          `json:"ct"`
+		Created     time.Time       `json:"created"`
+		Expires     time.Time       `json:"expires"` // zero for never
+		Burn        bool            `json:"burnafterreading"`
+		Discussion  bool            `json:"opendiscussion"`
+		DeleteToken string          `json:"deletetoken"`    // sha256 of the token handed out
+		Salt        string          `json:"salt,omitempty"` // the token is hmac-sha256(id, salt) when DeleteToken is empty
+		// version 1 pastes keep the sjcl json in Data and their options in clear
+		Data           string `json:"data,omitempty"`
+		Formatter      string `json:"formatter,omitempty"`
+		Attachment     string `json:"attachment,omitempty"`
+		AttachmentName string `json:"attachmentname,omitempty"`
+	}
+	// Comment is a stored comment, ParentID is the paste id for top level ones
+	Comment struct {
+		ID       string          `json:"id"`
+		PasteID  string          `json:"pasteid"`
+		ParentID string          `json:"parentid"`
+		V        int             `json:"v"`
+		AData    json.RawMessage `json:"adata"`
+		CT       string          `json:"ct"`
+		Created  time.Time       `json:"created"`
+		Data     string          `json:"data,omitempty"`     // sjcl json of version 1
+		Nickname string          `json:"nickname,omitempty"` // sjcl json of version 1
+	}
+	// Memory is a Storage that forgets everything on exit
+	Memory struct {
+		pastes   map[string]Paste
+		comments map[string][]Comment
+		sync.Mutex
+	}
+)
+
+func NewMemory() *Memory {
+	return &Memory{
+		pastes:   map[string]Paste{},
+		comments: map[string][]Comment{},
+	}
+}
+
+func (m *Memory) Create(p *Paste) error {
+	m.Lock()
+	defer m.Unlock()
+	if _, ok := m.pastes[p.ID]; ok {
+		return ErrExists
+	}
+	m.pastes[p.ID] = *p
+	return nil
+}
+
+func (m *Memory) Read(id string) (*Paste, error) {
+	m.Lock()
+	defer m.Unlock()
+	p, ok := m.pastes[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &p, nil
+}
+
+func (m *Memory) Delete(id string) error {
+	m.Lock()
+	defer m.Unlock()
+	if _, ok := m.pastes[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.pastes, id)
+	delete(m.comments, id)
+	return nil
+}
+
+func (m *Memory) AddComment(c *Comment) error {
+	m.Lock()
+	defer m.Unlock()
+	if _, ok := m.pastes[c.PasteID]; !ok {
+		return ErrNotFound
+	}
+	m.comments[c.PasteID] = append(m.comments[c.PasteID], *c)
+	return nil
+}
+
+func (m *Memory) Comments(pasteID string) ([]*Comment, error) {
+	m.Lock()
+	defer m.Unlock()
+	cs := []*Comment{}
+	for _, c := range m.comments[pasteID] {
+		c := c
+		cs = append(cs, &c)
+	}
+	sort.SliceStable(cs, func(i, j int) bool {
+		return cs[i].Created.Before(cs[j].Created)
+	})
+	return cs, nil
+}
+
+func (m *Memory) List() ([]string, error) {
+	m.Lock()
+	defer m.Unlock()
+	ids := []string{}
+	for id := range m.pastes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
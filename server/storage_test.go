@@ -0,0 +1,71 @@
+package server
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStorages(t *testing.T) {
+	fs, err := NewFilesystem(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, err := NewPrivateBinDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	for name, st := range map[string]Storage{"memory": NewMemory(), "filesystem": fs, "privatebin": pb} {
+		p := &Paste{
+			ID:         "0011223344556677",
+			V:          2,
+			AData:      []byte(`[` + testSpec + `,"plaintext",1,0]`),
+			CT:         "AAAA",
+			Created:    now,
+			Expires:    now.Add(time.Hour),
+			Discussion: true,
+			Salt:       "salt",
+		}
+		if err = st.Create(p); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if err = st.Create(p); !errors.Is(err, ErrExists) {
+			t.Errorf("%s: second create got %v", name, err)
+		}
+		got, err := st.Read(p.ID)
+		if err != nil || got.CT != p.CT || !got.Created.Equal(now) || !got.Expires.Equal(p.Expires) || !got.Discussion || got.Salt != "salt" {
+			t.Errorf("%s: read %+v, %v", name, got, err)
+		}
+		if _, err = st.Read("8899aabbccddeeff"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: missing paste got %v", name, err)
+		}
+		for i, id := range []string{"aaaaaaaaaaaaaaaa", "bbbbbbbbbbbbbbbb"} {
+			err = st.AddComment(&Comment{ID: id, PasteID: p.ID, ParentID: p.ID, V: 2, AData: []byte(testSpec), CT: "AAAA", Created: now.Add(time.Duration(1-i) * time.Second)})
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+		if err = st.AddComment(&Comment{ID: "cccccccccccccccc", PasteID: "8899aabbccddeeff", ParentID: "8899aabbccddeeff"}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: comment on a missing paste got %v", name, err)
+		}
+		cs, err := st.Comments(p.ID)
+		if err != nil || len(cs) != 2 || cs[0].ID != "bbbbbbbbbbbbbbbb" {
+			t.Errorf("%s: comments %v, %v, oldest first", name, cs, err)
+		}
+		ids, err := st.List()
+		if err != nil || !reflect.DeepEqual(ids, []string{p.ID}) {
+			t.Errorf("%s: list %v, %v", name, ids, err)
+		}
+		if err = st.Delete(p.ID); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if cs, err = st.Comments(p.ID); err != nil || len(cs) != 0 {
+			t.Errorf("%s: comments left %v, %v", name, cs, err)
+		}
+		if err = st.Delete(p.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: second delete got %v", name, err)
+		}
+	}
+}
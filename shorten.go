@@ -0,0 +1,74 @@
+package pbin
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	pasteURLRegexp = regexp.MustCompile(`id="pasteurl"[^>]*href="([^"]+)"`)
+	anyURLRegexp   = regexp.MustCompile(`https?://[^\s"'<>]+`)
+)
+
+func (h *host) shortenerURL(link string) string {
+	if h.shortener != "" {
+		return h.shortener + url.QueryEscape(link)
+	}
+	return h.api.String() + "?shortenviayourls&link=" + url.QueryEscape(link)
+}
+
+// shorten asks the shortener of the host for a short version of link
+func (c *Client) shorten(ctx context.Context, h *host, link string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.shortenerURL(link), nil)
+	if err != nil {
+		return "", err
+	}
+	res, err := c.do(req)
+	if err != nil {
+		// the request url carries the paste key, keep it out of the error
+		return "", &ServerError{Host: h.api.String(), Kind: ErrShortener, Cause: stripURL(err)}
+	}
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", &ServerError{Host: h.api.String(), StatusCode: res.StatusCode, Kind: ErrShortener, Cause: err}
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", &ServerError{Host: h.api.String(), StatusCode: res.StatusCode, Kind: ErrShortener}
+	}
+	short := extractShortURL(string(b), link)
+	if short == "" {
+		return "", &ServerError{Host: h.api.String(), StatusCode: res.StatusCode, Kind: ErrShortener, Cause: errors.New("no short url in response")}
+	}
+	return short, nil
+}
+
+// extractShortURL looks for the link privatebin renders for yourls,
+// otherwise it takes the shortest url in the response like the web ui does
+func extractShortURL(body string, link string) string {
+	if m := pasteURLRegexp.FindStringSubmatch(body); m != nil {
+		return strings.ReplaceAll(m[1], "&amp;", "&")
+	}
+	urls := []string{}
+	for _, u := range anyURLRegexp.FindAllString(body, -1) {
+		if u == link || strings.Contains(link, u) {
+			continue
+		}
+		if _, err := url.Parse(u); err == nil {
+			urls = append(urls, u)
+		}
+	}
+	sort.SliceStable(urls, func(i, j int) bool {
+		return len(urls[i]) < len(urls[j])
+	})
+	if len(urls) == 0 {
+		return ""
+	}
+	return urls[0]
+}
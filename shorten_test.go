@@ -0,0 +1,91 @@
+package pbin
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cbluth/pbin/server"
+)
+
+// shortenHost serves the api and answers ?shortenviayourls like privatebin does with yourls
+func shortenHost(t *testing.T, answer string) (*testHost, *string) {
+	st := server.NewMemory()
+	api := server.New(st)
+	asked := new(string)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.URL.Query()["shortenviayourls"]; ok {
+			*asked = r.URL.Query().Get("link")
+			w.Write([]byte(answer))
+			return
+		}
+		api.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	return &testHost{Server: ts, storage: st}, asked
+}
+
+func TestShorten(t *testing.T) {
+	pings := int32(0)
+	c := copiesClient(t, &pings)
+	plain := newTestHost(t)
+	addTestHost(t, c, plain, false)
+	h, asked := shortenHost(t, `<p>Your document is <a id="pasteurl" href="https://s.example.org/x?a=1&amp;b=2">here</a></p>`)
+	u, _ := parseHostURL(h.URL + "/")
+	c.hosts.addHost(&host{api: u, expiry: []Expiry{Week}, features: []Feature{ShortenURL}})
+	p, _ := c.CraftPaste([]byte("short"))
+	p.Shorten(true)
+	r, err := p.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only the host with a shortener is picked
+	if r.Host != h.URL+"/" || plain.pastes(t) != 0 {
+		t.Fatalf("sent to %s", r.Host)
+	}
+	if r.ShortURL != "https://s.example.org/x?a=1&b=2" || p.ShortURL() != r.ShortURL || *asked != r.URL {
+		t.Errorf("short url %q for %q, shortener asked for %q", r.ShortURL, r.URL, *asked)
+	}
+}
+
+func TestShortenFails(t *testing.T) {
+	pings := int32(0)
+	c := copiesClient(t, &pings)
+	h, _ := shortenHost(t, "<p>yourls is not configured</p>")
+	u, _ := parseHostURL(h.URL + "/")
+	c.hosts.addHost(&host{api: u, expiry: []Expiry{Week}, features: []Feature{ShortenURL}})
+	p, _ := c.CraftPaste([]byte("short"))
+	p.Shorten(true)
+	r, err := p.Send()
+	// the paste is up, only the short link is missing
+	if !errors.Is(err, ErrShortener) || r == nil || r.URL == "" || h.pastes(t) != 1 {
+		t.Fatalf("got %+v, %v", r, err)
+	}
+	if strings.Contains(err.Error(), r.URL) {
+		t.Errorf("the paste url is in %q", err)
+	}
+	// without a host that shortens there is none to send to
+	c = copiesClient(t, &pings)
+	addTestHost(t, c, newTestHost(t), false)
+	p, _ = c.CraftPaste([]byte("short"))
+	p.Shorten(true)
+	if _, err = p.Send(); !errors.Is(err, ErrNoHost) {
+		t.Errorf("got %v, want ErrNoHost", err)
+	}
+}
+
+func TestExtractShortURL(t *testing.T) {
+	link := "https://paste.example.org/?0011#key"
+	for body, want := range map[string]string{
+		`{"shorturl":"https://sho.rt/ab"}`:                              "https://sho.rt/ab",
+		"see https://longer.example.org/abc or https://sho.rt/ab":       "https://sho.rt/ab",
+		"https://paste.example.org/?0011#key is at https://sho.rt/abcd": "https://sho.rt/abcd",
+		"nothing here": "",
+	} {
+		if got := extractShortURL(body, link); got != want {
+			t.Errorf("%s: got %q, want %q", body, got, want)
+		}
+	}
+}
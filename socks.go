@@ -0,0 +1,57 @@
+package pbin
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/proxy"
+)
+
+// SetSOCKS5 routes the requests and host pings of the default client through a SOCKS5 proxy
+func SetSOCKS5(addr string, user string, pass string) error {
+	return DefaultClient.SetSOCKS5(addr, user, pass)
+}
+
+// SetSOCKS5 routes the requests and host pings through the SOCKS5 proxy at addr,
+// for example a local tor at 127.0.0.1:9050. Host names are resolved by the proxy
+// so .onion hosts work. user and pass may be empty, tor uses them to isolate circuits.
+// It clones the transport of HTTPClient and only swaps its dialer, custom root CAs and
+// timeouts are kept
+func (c *Client) SetSOCKS5(addr string, user string, pass string) error {
+	auth := (*proxy.Auth)(nil)
+	if user != "" || pass != "" {
+		auth = &proxy.Auth{User: user, Password: pass}
+	}
+	d, err := proxy.SOCKS5("tcp", addr, auth, &net.Dialer{})
+	if err != nil {
+		return err
+	}
+	cd, ok := d.(proxy.ContextDialer)
+	if !ok {
+		return errors.New("socks5 dialer does not support contexts")
+	}
+	hc := &http.Client{}
+	if c.HTTPClient != nil {
+		*hc = *c.HTTPClient
+	}
+	rt := hc.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		return errors.New("socks5 needs an *http.Transport in HTTPClient")
+	}
+	// tls config and timeouts stay, an http proxy would bypass the socks one
+	t = t.Clone()
+	t.DialContext = cd.DialContext
+	t.Dial = nil
+	t.DialTLS = nil
+	t.DialTLSContext = nil
+	t.Proxy = nil
+	hc.Transport = t
+	c.HTTPClient = hc
+	c.dial = cd.DialContext
+	return nil
+}
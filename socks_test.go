@@ -0,0 +1,167 @@
+package pbin
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// socksServer is a bare socks5 proxy without auth that counts the connections it forwards
+type socksServer struct {
+	ln    net.Listener
+	conns int32
+}
+
+func newSOCKSServer(t *testing.T) *socksServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &socksServer{ln: ln}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *socksServer) serve(c net.Conn) {
+	defer c.Close()
+	b := make([]byte, 262)
+	// greeting: version, method count, methods
+	if _, err := io.ReadFull(c, b[:2]); err != nil || b[0] != 5 {
+		return
+	}
+	if _, err := io.ReadFull(c, b[:b[1]]); err != nil {
+		return
+	}
+	c.Write([]byte{5, 0})
+	// request: version, connect, reserved, address type
+	if _, err := io.ReadFull(c, b[:4]); err != nil || b[1] != 1 {
+		return
+	}
+	host := ""
+	switch b[3] {
+	case 1, 4:
+		{
+			n := 4
+			if b[3] == 4 {
+				n = 16
+			}
+			if _, err := io.ReadFull(c, b[:n]); err != nil {
+				return
+			}
+			host = net.IP(b[:n]).String()
+		}
+	case 3:
+		{
+			if _, err := io.ReadFull(c, b[:1]); err != nil {
+				return
+			}
+			n := int(b[0])
+			if _, err := io.ReadFull(c, b[:n]); err != nil {
+				return
+			}
+			host = string(b[:n])
+		}
+	default:
+		{
+			return
+		}
+	}
+	if _, err := io.ReadFull(c, b[:2]); err != nil {
+		return
+	}
+	port := binary.BigEndian.Uint16(b[:2])
+	u, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		c.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer u.Close()
+	atomic.AddInt32(&s.conns, 1)
+	c.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	go io.Copy(u, c)
+	io.Copy(c, u)
+}
+
+func TestSetSOCKS5KeepsTLSConfig(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	// the ping below hangs up before the handshake
+	ts.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	ts.StartTLS()
+	defer ts.Close()
+	proxy := newSOCKSServer(t)
+	// the test server certificate is only trusted through the root CAs of ts.Client
+	hc := ts.Client()
+	hc.Timeout = 7 * time.Second
+	c := NewClient(hc)
+	err := c.SetSOCKS5(proxy.ln.Addr().String(), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.HTTPClient.Timeout != 7*time.Second {
+		t.Error("client timeout was dropped")
+	}
+	tr := c.HTTPClient.Transport.(*http.Transport)
+	if tr.TLSClientConfig == nil || tr.TLSClientConfig.RootCAs == nil {
+		t.Fatal("tls config was dropped")
+	}
+	if http.RoundTripper(tr) == hc.Transport {
+		t.Error("the transport of the caller was changed")
+	}
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	res, err := c.do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if string(b) != "ok" {
+		t.Fatalf("got %q", b)
+	}
+	if atomic.LoadInt32(&proxy.conns) != 1 {
+		t.Fatalf("proxy forwarded %d connections, want 1", proxy.conns)
+	}
+	// host pings take the proxy too
+	conn, err := c.dialer()(context.Background(), "tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if atomic.LoadInt32(&proxy.conns) != 2 {
+		t.Fatalf("ping did not go through the proxy")
+	}
+}
+
+func TestSetSOCKS5CustomRoundTripper(t *testing.T) {
+	c := NewClient(&http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, io.EOF
+	})})
+	if c.SetSOCKS5("127.0.0.1:1", "", "") == nil {
+		t.Fatal("a transport that can not take a dialer was replaced")
+	}
+}
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}